@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// functions aggregating the values of all the nodes matched by the selector of a metric into a single sample
+const (
+	aggregateSum    = "sum"
+	aggregateAvg    = "avg"
+	aggregateMin    = "min"
+	aggregateMax    = "max"
+	aggregateCount  = "count"
+	aggregateMedian = "median"
+)
+
+var aggregateFunctions = map[string]bool{
+	aggregateSum:    true,
+	aggregateAvg:    true,
+	aggregateMin:    true,
+	aggregateMax:    true,
+	aggregateCount:  true,
+	aggregateMedian: true,
+}
+
+func validateAggregate(metricConfig types.MetricConfig) error {
+	if metricConfig.Aggregate == "" {
+		return nil
+	}
+
+	if !aggregateFunctions[metricConfig.Aggregate] {
+		return fmt.Errorf("unknown aggregate \"%s\" in metric \"%s\"", metricConfig.Aggregate, metricConfig.Name)
+	}
+
+	// these export their own samples, which can't be combined into one
+	if len(metricConfig.LabelSelectors) > 0 || metricConfig.Table != nil || len(metricConfig.States) > 0 ||
+		metricConfig.Expression != "" || metricConfig.Type == metricTypeInfo ||
+		metricConfig.Mode == metricModePresence || metricConfig.Mode == metricModeCount || metricConfig.Extract == extractEvaluate {
+		return fmt.Errorf("metric \"%s\" can't be aggregated, since aggregates can't be combined with label selectors, tables, states, expressions, info metrics, the presence and count modes or evaluate", metricConfig.Name)
+	}
+
+	return nil
+}
+
+// aggregateValues parses the values of `count` nodes with `parseValue`, and exports their aggregate as a single sample.
+// values that fail to parse fail the scrape, unless the metric skips invalid values
+func aggregateValues(count int, parseValue func(i int) (float64, error), metricConfig types.MetricConfig) ([]metricValue, error) {
+	numbers := make([]float64, 0, count)
+
+	for i := 0; i < count; i++ {
+		number, err := parseValue(i)
+		if err != nil {
+			if !metricConfig.SkipInvalidValues {
+				return nil, err
+			}
+
+			log.Debugf("skipping value %d of metric '%s' from the %s. error: %s", i+1, metricConfig.Name, metricConfig.Aggregate, err)
+			continue
+		}
+
+		numbers = append(numbers, number)
+	}
+
+	value, err := aggregate(numbers, metricConfig.Aggregate)
+	if err != nil {
+		return nil, err
+	}
+
+	return []metricValue{{metricConfig: metricConfig, value: value, labels: metricConfig.Labels}}, nil
+}
+
+func aggregate(numbers []float64, function string) (float64, error) {
+	switch function {
+	case aggregateCount:
+		return float64(len(numbers)), nil
+	case aggregateSum:
+		sum := 0.0
+		for _, number := range numbers {
+			sum += number
+		}
+
+		return sum, nil
+	}
+
+	if len(numbers) == 0 {
+		return 0, newScrapeError(failureReasonNumberFormat, "no valid values to aggregate with %s", function)
+	}
+
+	switch function {
+	case aggregateAvg:
+		sum, _ := aggregate(numbers, aggregateSum)
+		return sum / float64(len(numbers)), nil
+	case aggregateMin:
+		min := math.Inf(1)
+		for _, number := range numbers {
+			min = math.Min(min, number)
+		}
+
+		return min, nil
+	case aggregateMax:
+		max := math.Inf(-1)
+		for _, number := range numbers {
+			max = math.Max(max, number)
+		}
+
+		return max, nil
+	case aggregateMedian:
+		sorted := append([]float64(nil), numbers...)
+		sort.Float64s(sorted)
+
+		middle := len(sorted) / 2
+		if len(sorted)%2 == 0 {
+			return (sorted[middle-1] + sorted[middle]) / 2, nil
+		}
+
+		return sorted[middle], nil
+	}
+
+	return 0, fmt.Errorf("unknown aggregate \"%s\"", function)
+}
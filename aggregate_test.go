@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+)
+
+func TestAggregate(t *testing.T) {
+	numbers := []float64{4, 1, 3, 2}
+
+	tests := []struct {
+		function string
+		numbers  []float64
+		expected float64
+	}{
+		{"sum", numbers, 10},
+		{"avg", numbers, 2.5},
+		{"min", numbers, 1},
+		{"max", numbers, 4},
+		{"count", numbers, 4},
+		{"median", numbers, 2.5},
+		{"median", []float64{5, 1, 3}, 3},
+		{"sum", nil, 0},
+		{"count", nil, 0},
+	}
+
+	for _, test := range tests {
+		value, err := aggregate(test.numbers, test.function)
+		ok(t, err)
+		equals(t, test.expected, value)
+	}
+
+	_, err := aggregate(nil, "avg")
+	errorContains(t, err, "no valid values to aggregate with avg")
+	equals(t, failureReasonNumberFormat, getFailureReason(err))
+}
+
+func TestScrape_aggregate(t *testing.T) {
+	server := getTestServer(`<table>
+<tr><td class="size">1,000</td></tr>
+<tr><td class="size">250.5</td></tr>
+<tr><td class="size">n/a</td></tr>
+<tr><td class="size">2 KiB</td></tr>
+</table>`)
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Metrics = []types.MetricConfig{
+		{Name: "total", Selector: "//td[@class='size'][. != 'n/a' and not(contains(., 'KiB'))]", Aggregate: "sum"},
+		{Name: "max", Selector: "//td[@class='size']", Aggregate: "max", Unit: "bytes", SkipInvalidValues: true},
+		{Name: "valid", Selector: "//td[@class='size']", Aggregate: "count", SkipInvalidValues: true},
+	}
+
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+	equals(t, 3, len(output))
+
+	equals(t, 1250.5, output[0].value)
+	equals(t, 2048.0, output[1].value)
+	equals(t, 2.0, output[2].value)
+}
+
+func TestScrape_aggregateInvalidValue(t *testing.T) {
+	server := getTestServer(`<ul><li>1</li><li>n/a</li></ul>`)
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Metrics = []types.MetricConfig{{Name: "total", Selector: "//li", Aggregate: "sum"}}
+
+	_, _, err := scrape(context.Background(), config)
+	errorContains(t, err, "error parsing value n/a")
+	equals(t, failureReasonNumberFormat, getFailureReason(err))
+}
+
+func TestScrape_aggregateNoMatch(t *testing.T) {
+	server := getTestServer(`<ul></ul>`)
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Metrics = []types.MetricConfig{
+		{Name: "total", Selector: "//li", Aggregate: "sum"},
+		{Name: "items", Selector: "//li", Aggregate: "count"},
+	}
+
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+	equals(t, 2, len(output))
+	equals(t, 0.0, output[0].value)
+	equals(t, 0.0, output[1].value)
+
+	for _, function := range []string{"avg", "min", "max", "median"} {
+		config.Metrics = []types.MetricConfig{{Name: "items", Selector: "//li", Aggregate: function}}
+
+		_, _, err = scrape(context.Background(), config)
+		errorContains(t, err, "no elements returned by the XPath expression")
+		equals(t, failureReasonXPath, getFailureReason(err))
+	}
+}
+
+func TestScrape_aggregateJSONAndXML(t *testing.T) {
+	jsonConfig := getTestJSONScrapeConfig(types.MetricConfig{Name: "avg_latency", Selector: "$.services[*]", ValueSelector: "@.latency", Aggregate: "avg"})
+
+	output, _, err := scrape(context.Background(), jsonConfig)
+	ok(t, err)
+	assertFloatEquals(t, (12.5+3+0.8)/3, output[0].value)
+
+	xmlConfig := getTestXMLScrapeConfig(types.MetricConfig{Name: "total_size", Selector: "//ns:queue/@size", Aggregate: "sum"})
+
+	output, _, err = scrape(context.Background(), xmlConfig)
+	ok(t, err)
+	equals(t, 1241.0, output[0].value)
+}
+
+func TestParseConfig_aggregate(t *testing.T) {
+	tests := []struct {
+		metric        string
+		expectedError string
+	}{
+		{"aggregate: mean", "unknown aggregate \"mean\""},
+		{"aggregate: sum\n      label_selectors:\n        foo: ./bar", "can't be aggregated"},
+		{"aggregate: sum\n      mode: count", "can't be aggregated"},
+	}
+
+	for _, test := range tests {
+		config := []byte(`
+scrape_config:
+  address: "http://foo.dev"
+  metrics:
+    - name: foo
+      ` + test.metric + `
+`)
+
+		_, err := parseConfig(config)
+		errorContains(t, err, test.expectedError)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+)
+
+// applyAuth sets the credentials of the scrape config in the request. secrets are read on every request,
+// so rotated files and environment variables are picked up without a reload
+func applyAuth(req *http.Request, config types.ScrapeConfig) error {
+	if config.BasicAuth != nil {
+		password, err := readSecret(config.BasicAuth.Password, config.BasicAuth.PasswordFile, config.BasicAuth.PasswordEnv)
+		if err != nil {
+			return fmt.Errorf("error reading basic auth password. %s", err)
+		}
+
+		req.SetBasicAuth(config.BasicAuth.Username, password)
+	}
+
+	bearerToken, err := readSecret(config.BearerToken, config.BearerTokenFile, config.BearerTokenEnv)
+	if err != nil {
+		return fmt.Errorf("error reading bearer token. %s", err)
+	}
+
+	if bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+bearerToken)
+	}
+
+	if config.HeaderAuth != nil {
+		value, err := readSecret(config.HeaderAuth.Value, config.HeaderAuth.ValueFile, config.HeaderAuth.ValueEnv)
+		if err != nil {
+			return fmt.Errorf("error reading auth header %s. %s", config.HeaderAuth.Name, err)
+		}
+
+		req.Header.Set(config.HeaderAuth.Name, value)
+	}
+
+	return nil
+}
+
+// readSecret returns the secret set directly in the config, or read from a file or an environment variable
+func readSecret(value string, file string, env string) (string, error) {
+	if file != "" {
+		fileBytes, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("unable to read secret file %s. error: %s", file, err)
+		}
+
+		return strings.TrimSpace(string(fileBytes)), nil
+	}
+
+	if env != "" {
+		secret, found := os.LookupEnv(env)
+		if !found {
+			return "", fmt.Errorf("environment variable %s is not set", env)
+		}
+
+		return secret, nil
+	}
+
+	return value, nil
+}
+
+// redactURL hides the password of URLs with credentials, so they can be logged
+func redactURL(address string) string {
+	parsedURL, err := url.Parse(address)
+	if err != nil {
+		return address
+	}
+
+	return parsedURL.Redacted()
+}
@@ -1,50 +1,184 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
 	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	probeSuccessDesc = prometheus.NewDesc(
+		"probe_success",
+		"Whether the scrape of the target succeeded",
+		[]string{"target"},
+		nil,
+	)
+	probeFailureReasonDesc = prometheus.NewDesc(
+		"probe_failure_reason",
+		"Reason of the failure when the scrape of the target did not succeed",
+		[]string{"target", "reason"},
+		nil,
+	)
+	probeDurationDesc = prometheus.NewDesc(
+		"probe_duration_seconds",
+		"Duration of each phase of the scrape of the target",
+		[]string{"target", "phase"},
+		nil,
+	)
+	probeHTTPStatusCodeDesc = prometheus.NewDesc(
+		"probe_http_status_code",
+		"Status code of the HTTP response of the target",
+		[]string{"target"},
+		nil,
+	)
+	probeHTTPResponseSizeDesc = prometheus.NewDesc(
+		"probe_http_response_size_bytes",
+		"Size of the HTTP response body of the target",
+		[]string{"target"},
+		nil,
+	)
+	probeSSLEarliestCertExpiryDesc = prometheus.NewDesc(
+		"probe_ssl_earliest_cert_expiry",
+		"Expiry of the earliest expiring certificate presented by the target, in unixtime",
+		[]string{"target"},
+		nil,
+	)
+	probeMatchedNodesDesc = prometheus.NewDesc(
+		"probe_matched_nodes",
+		"Number of nodes matched by the selector of each metric",
+		[]string{"target", "metric"},
+		nil,
+	)
 )
 
 type collector struct {
-	config types.ExporterConfig
+	// ctx bounds the scrapes of the collector, e.g. to the Prometheus scrape timeout
+	ctx           context.Context
+	scrapeConfigs []types.ScrapeConfig
+	globalConfig  types.GlobalConfig
 }
 
 func (c collector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- makeMetricDesc(c.config)
+	ch <- probeSuccessDesc
+	ch <- probeFailureReasonDesc
+	ch <- probeDurationDesc
+	ch <- probeHTTPStatusCodeDesc
+	ch <- probeHTTPResponseSizeDesc
+	ch <- probeSSLEarliestCertExpiryDesc
+	ch <- probeMatchedNodesDesc
+
+	for _, scrapeConfig := range c.scrapeConfigs {
+		for _, metricConfig := range getMetricConfigs(scrapeConfig) {
+			ch <- makeMetricDesc(metricConfig, c.globalConfig)
+		}
+	}
 }
 
+// Collect scrapes the targets concurrently, so a slow target doesn't delay the others past the probe timeout
 func (c collector) Collect(ch chan<- prometheus.Metric) {
-	value, err := scrape(c.config.ScrapeConfig)
+	var wg sync.WaitGroup
+
+	for _, scrapeConfig := range c.scrapeConfigs {
+		wg.Add(1)
+
+		go func(scrapeConfig types.ScrapeConfig) {
+			defer wg.Done()
+			c.collectTarget(ch, scrapeConfig)
+		}(scrapeConfig)
+	}
+
+	wg.Wait()
+}
+
+func (c collector) collectTarget(ch chan<- prometheus.Metric, scrapeConfig types.ScrapeConfig) {
+	start := time.Now()
+	metrics, stats, err := c.collectScrapeConfig(scrapeConfig)
+
+	probesTotal.WithLabelValues(scrapeConfig.Name).Inc()
+	probeDurationSeconds.WithLabelValues(scrapeConfig.Name).Observe(time.Since(start).Seconds())
+	collectScrapeStats(ch, scrapeConfig.Name, stats)
 
 	if err != nil {
-		// @TODO: better handling
-		panic(fmt.Sprintf("error scraping: %s", err.Error()))
+		reason := getFailureReason(err)
+		log.Errorf("error scraping target \"%s\" (reason: %s): %s", scrapeConfig.Name, reason, err)
+		probeFailuresTotal.WithLabelValues(scrapeConfig.Name, reason).Inc()
+
+		ch <- prometheus.MustNewConstMetric(probeSuccessDesc, prometheus.GaugeValue, 0, scrapeConfig.Name)
+		ch <- prometheus.MustNewConstMetric(probeFailureReasonDesc, prometheus.GaugeValue, 1, scrapeConfig.Name, reason)
+		return
 	}
 
-	metric, err := makeNewConstMetric(c.config, value)
+	for _, metric := range metrics {
+		ch <- metric
+	}
 
+	ch <- prometheus.MustNewConstMetric(probeSuccessDesc, prometheus.GaugeValue, 1, scrapeConfig.Name)
+}
+
+// collectScrapeConfig scrapes the target and builds all of its metrics, so none are exported if any of them fails
+func (c collector) collectScrapeConfig(scrapeConfig types.ScrapeConfig) ([]prometheus.Metric, *scrapeStats, error) {
+	metricValues, stats, err := scrape(c.ctx, scrapeConfig)
 	if err != nil {
-		panic(err.Error())
+		return nil, stats, err
+	}
+
+	metrics := make([]prometheus.Metric, len(metricValues))
+	sampleKeys := make(map[string]bool, len(metricValues))
+
+	for i, metricValue := range metricValues {
+		// e.g. label selectors extracting the same value from two rows, which Prometheus would reject as a whole
+		sampleKey := metricValue.metricConfig.Name + "\xff" + strings.Join(getLabelValues(metricValue.labels), "\xff")
+		if sampleKeys[sampleKey] {
+			return nil, stats, newScrapeError(failureReasonMetric, "duplicate sample of metric \"%s\" with labels %v", metricValue.metricConfig.Name, metricValue.labels)
+		}
+
+		sampleKeys[sampleKey] = true
+
+		metric, err := makeNewConstMetric(metricValue.metricConfig, c.globalConfig, metricValue.value, metricValue.labels)
+		if err != nil {
+			return nil, stats, scrapeError{reason: failureReasonMetric, err: err}
+		}
+
+		metrics[i] = metric
 	}
 
-	ch <- metric
+	return metrics, stats, nil
 }
 
-func makeMetricDesc(config types.ExporterConfig) *prometheus.Desc {
-	metricConfig := config.ScrapeConfig.MetricConfig
+func collectScrapeStats(ch chan<- prometheus.Metric, target string, stats *scrapeStats) {
+	for _, phase := range scrapePhases {
+		ch <- prometheus.MustNewConstMetric(probeDurationDesc, prometheus.GaugeValue, stats.getPhaseDuration(phase).Seconds(), target, phase)
+	}
+
+	ch <- prometheus.MustNewConstMetric(probeHTTPStatusCodeDesc, prometheus.GaugeValue, float64(stats.statusCode), target)
+	ch <- prometheus.MustNewConstMetric(probeHTTPResponseSizeDesc, prometheus.GaugeValue, float64(stats.bodySize), target)
 
+	if !stats.certExpiry.IsZero() {
+		ch <- prometheus.MustNewConstMetric(probeSSLEarliestCertExpiryDesc, prometheus.GaugeValue, float64(stats.certExpiry.Unix()), target)
+	}
+
+	for metric, matchedNodes := range stats.matchedNodes {
+		ch <- prometheus.MustNewConstMetric(probeMatchedNodesDesc, prometheus.GaugeValue, float64(matchedNodes), target, metric)
+	}
+}
+
+func makeMetricDesc(metricConfig types.MetricConfig, globalConfig types.GlobalConfig) *prometheus.Desc {
 	return prometheus.NewDesc(
-		config.GlobalConfig.MetricNamePrefix+metricConfig.Name,
+		globalConfig.MetricNamePrefix+metricConfig.Name,
 		metricConfig.Help,
-		getLabelKeys(metricConfig.Labels),
+		getMetricLabelKeys(metricConfig),
 		nil,
 	)
 }
 
-func makeNewConstMetric(config types.ExporterConfig, value float64) (prometheus.Metric, error) {
-	metricConfig := config.ScrapeConfig.MetricConfig
+func makeNewConstMetric(metricConfig types.MetricConfig, globalConfig types.GlobalConfig, value float64, labels map[string]string) (prometheus.Metric, error) {
 	var valueType prometheus.ValueType
 
 	switch metricConfig.Type {
@@ -55,9 +189,14 @@ func makeNewConstMetric(config types.ExporterConfig, value float64) (prometheus.
 		valueType = getPrometheusValueType(metricConfig.Type)
 	}
 
-	desc := makeMetricDesc(config)
+	// state sets are exposed as gauges, as Prometheus has no type for them
+	if len(metricConfig.States) > 0 {
+		valueType = prometheus.GaugeValue
+	}
+
+	desc := makeMetricDesc(metricConfig, globalConfig)
 
-	labelValues := getLabelValues(metricConfig.Labels)
+	labelValues := getLabelValues(labels)
 	metric, err := prometheus.NewConstMetric(desc, valueType, value, labelValues...)
 
 	if err != nil {
@@ -67,6 +206,34 @@ func makeNewConstMetric(config types.ExporterConfig, value float64) (prometheus.
 	return metric, nil
 }
 
+// getMetricLabelKeys returns the names of the static labels, label selectors, table label columns and state label of a metric
+func getMetricLabelKeys(metricConfig types.MetricConfig) []string {
+	labels := make(map[string]string, len(metricConfig.Labels)+len(metricConfig.LabelSelectors))
+
+	for k, v := range metricConfig.Labels {
+		labels[k] = v
+	}
+
+	for k := range metricConfig.LabelSelectors {
+		labels[k] = ""
+	}
+
+	if metricConfig.Table != nil {
+		for k := range metricConfig.Table.LabelColumns {
+			labels[k] = ""
+		}
+
+		labels[tableColumnLabel] = ""
+	}
+
+	if len(metricConfig.States) > 0 {
+		labels[stateLabel] = ""
+	}
+
+	return getLabelKeys(labels)
+}
+
+// getLabelKeys returns the label names sorted, so they match the order of getLabelValues
 func getLabelKeys(labels map[string]string) []string {
 	labelKeys := make([]string, len(labels))
 
@@ -76,16 +243,17 @@ func getLabelKeys(labels map[string]string) []string {
 		i++
 	}
 
+	sort.Strings(labelKeys)
+
 	return labelKeys
 }
 
 func getLabelValues(labels map[string]string) []string {
-	labelValues := make([]string, len(labels))
+	labelKeys := getLabelKeys(labels)
+	labelValues := make([]string, len(labelKeys))
 
-	i := 0
-	for _, v := range labels {
-		labelValues[i] = v
-		i++
+	for i, k := range labelKeys {
+		labelValues[i] = labels[k]
 	}
 
 	return labelValues
@@ -94,7 +262,7 @@ func getLabelValues(labels map[string]string) []string {
 func getPrometheusValueType(metricType string) prometheus.ValueType {
 	var valueType prometheus.ValueType
 
-	if metricType == "gauge" {
+	if metricType == "gauge" || metricType == metricTypeInfo {
 		valueType = prometheus.GaugeValue
 	} else if metricType == "counter" {
 		valueType = prometheus.CounterValue
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestCollect(t *testing.T) {
@@ -17,35 +22,217 @@ func TestCollect(t *testing.T) {
 		fmt.Fprintln(w, html)
 	}))
 
-	config := testExporterConfig
-	config.ScrapeConfig.Address = server.URL
+	scrapeConfig := testExporterConfig.ScrapeConfig
+	scrapeConfig.Address = server.URL
 
-	collector := collector{config: config}
+	collector := collector{ctx: context.Background(), scrapeConfigs: []types.ScrapeConfig{scrapeConfig}, globalConfig: testExporterConfig.GlobalConfig}
 
-	ch := make(chan prometheus.Metric, 1)
+	ch := make(chan prometheus.Metric, 32)
 
 	collector.Collect(ch)
 	metric := <-ch
 	assert(t, metric != nil, "collect should not return a nil metric")
 }
 
+func TestCollect_multipleScrapeConfigs(t *testing.T) {
+	server := getTestServer("<div id=\"foobar\">1,234,567.08</div>")
+
+	scrapeConfig := testExporterConfig.ScrapeConfig
+	scrapeConfig.Name = "foo"
+	scrapeConfig.Address = server.URL
+
+	otherScrapeConfig := scrapeConfig
+	otherScrapeConfig.Name = "bar"
+	otherScrapeConfig.MetricConfig.Name = "other_metric"
+
+	collector := collector{ctx: context.Background(), scrapeConfigs: []types.ScrapeConfig{scrapeConfig, otherScrapeConfig}, globalConfig: testExporterConfig.GlobalConfig}
+
+	count := testutil.CollectAndCount(collector, "htmlexporter_wikipedia_articles_total", "htmlexporter_other_metric")
+	equals(t, 2, count)
+}
+
+func TestCollect_concurrentScrapeConfigs(t *testing.T) {
+	var requests sync.WaitGroup
+	requests.Add(2)
+	allRequestsReceived := make(chan struct{})
+
+	go func() {
+		requests.Wait()
+		close(allRequestsReceived)
+	}()
+
+	// each response waits for the request of the other target, so they only succeed when scraped concurrently
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Done()
+
+		select {
+		case <-allRequestsReceived:
+			fmt.Fprintln(w, "<div id=\"foobar\">1</div>")
+		case <-time.After(time.Second):
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+
+	scrapeConfig := testExporterConfig.ScrapeConfig
+	scrapeConfig.Name = "foo"
+	scrapeConfig.Address = server.URL
+
+	otherScrapeConfig := scrapeConfig
+	otherScrapeConfig.Name = "bar"
+	otherScrapeConfig.MetricConfig.Name = "other_metric"
+
+	collector := collector{ctx: context.Background(), scrapeConfigs: []types.ScrapeConfig{scrapeConfig, otherScrapeConfig}, globalConfig: testExporterConfig.GlobalConfig}
+
+	expected := `
+# HELP probe_success Whether the scrape of the target succeeded
+# TYPE probe_success gauge
+probe_success{target="bar"} 1
+probe_success{target="foo"} 1
+`
+
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "probe_success")
+	ok(t, err)
+}
+
 func TestCollect_scrapeError(t *testing.T) {
-	config := testExporterConfig
-	config.ScrapeConfig.Address = "foo://bar.dev"
+	scrapeConfig := testExporterConfig.ScrapeConfig
+	scrapeConfig.Name = "foo"
+	scrapeConfig.Address = "foo://bar.dev"
 
-	collector := collector{config: config}
+	collector := collector{ctx: context.Background(), scrapeConfigs: []types.ScrapeConfig{scrapeConfig}, globalConfig: testExporterConfig.GlobalConfig}
 
-	defer func() { recover() }()
+	expected := `
+# HELP probe_failure_reason Reason of the failure when the scrape of the target did not succeed
+# TYPE probe_failure_reason gauge
+probe_failure_reason{reason="request",target="foo"} 1
+# HELP probe_success Whether the scrape of the target succeeded
+# TYPE probe_success gauge
+probe_success{target="foo"} 0
+`
 
-	ch := make(chan prometheus.Metric, 1)
+	failures := testutil.ToFloat64(probeFailuresTotal.WithLabelValues("foo", "request"))
 
-	collector.Collect(ch)
-	t.Errorf("should panic when configured with a summary metric (not implemented yet)")
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "probe_success", "probe_failure_reason")
+	ok(t, err)
+
+	equals(t, failures+1, testutil.ToFloat64(probeFailuresTotal.WithLabelValues("foo", "request")))
+}
+
+func TestCollect_probeSuccess(t *testing.T) {
+	server := getTestServer("<div id=\"foobar\">1,234,567.08</div>")
+
+	scrapeConfig := testExporterConfig.ScrapeConfig
+	scrapeConfig.Name = "foo"
+	scrapeConfig.Address = server.URL
+
+	collector := collector{ctx: context.Background(), scrapeConfigs: []types.ScrapeConfig{scrapeConfig}, globalConfig: testExporterConfig.GlobalConfig}
+
+	expected := `
+# HELP probe_success Whether the scrape of the target succeeded
+# TYPE probe_success gauge
+probe_success{target="foo"} 1
+`
+
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "probe_success", "probe_failure_reason")
+	ok(t, err)
+}
+
+func TestCollect_scrapeStats(t *testing.T) {
+	server := getTestServer("<div id=\"foobar\">1,234,567.08</div>")
+
+	scrapeConfig := testExporterConfig.ScrapeConfig
+	scrapeConfig.Name = "foo"
+	scrapeConfig.Address = server.URL
+
+	collector := collector{ctx: context.Background(), scrapeConfigs: []types.ScrapeConfig{scrapeConfig}, globalConfig: testExporterConfig.GlobalConfig}
+
+	expected := `
+# HELP probe_http_status_code Status code of the HTTP response of the target
+# TYPE probe_http_status_code gauge
+probe_http_status_code{target="foo"} 200
+# HELP probe_http_response_size_bytes Size of the HTTP response body of the target
+# TYPE probe_http_response_size_bytes gauge
+probe_http_response_size_bytes{target="foo"} 36
+# HELP probe_matched_nodes Number of nodes matched by the selector of each metric
+# TYPE probe_matched_nodes gauge
+probe_matched_nodes{metric="wikipedia_articles_total",target="foo"} 1
+`
+
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "probe_http_status_code", "probe_http_response_size_bytes", "probe_matched_nodes")
+	ok(t, err)
+
+	count := testutil.CollectAndCount(collector, "probe_duration_seconds")
+	equals(t, len(scrapePhases), count)
+}
+
+func TestCollect_metricError(t *testing.T) {
+	server := getTestServer("<div id=\"foobar\">1,234,567.08</div>")
+
+	scrapeConfig := testExporterConfig.ScrapeConfig
+	scrapeConfig.Name = "foo"
+	scrapeConfig.Address = server.URL
+	scrapeConfig.MetricConfig.Type = "summary"
+
+	otherScrapeConfig := testExporterConfig.ScrapeConfig
+	otherScrapeConfig.Name = "bar"
+	otherScrapeConfig.Address = server.URL
+
+	collector := collector{ctx: context.Background(), scrapeConfigs: []types.ScrapeConfig{scrapeConfig, otherScrapeConfig}, globalConfig: testExporterConfig.GlobalConfig}
+
+	expected := `
+# HELP probe_failure_reason Reason of the failure when the scrape of the target did not succeed
+# TYPE probe_failure_reason gauge
+probe_failure_reason{reason="metric",target="foo"} 1
+# HELP probe_success Whether the scrape of the target succeeded
+# TYPE probe_success gauge
+probe_success{target="bar"} 1
+probe_success{target="foo"} 0
+`
+
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "probe_success", "probe_failure_reason")
+	ok(t, err)
+}
+
+func TestCollect_duplicateLabelSets(t *testing.T) {
+	server := getTestServer("<table><tr><td>foo</td><td>1</td></tr><tr><td>foo</td><td>2</td></tr></table>")
+
+	scrapeConfig := testExporterConfig.ScrapeConfig
+	scrapeConfig.Name = "foo"
+	scrapeConfig.Address = server.URL
+	scrapeConfig.MetricConfig = types.MetricConfig{
+		Name:           "service_requests",
+		Type:           "gauge",
+		Selector:       "//tr/td[2]/text()",
+		LabelSelectors: map[string]string{"svc": "../../td[1]/text()"},
+	}
+
+	otherScrapeConfig := testExporterConfig.ScrapeConfig
+	otherScrapeConfig.Name = "bar"
+	otherScrapeConfig.Address = server.URL
+	otherScrapeConfig.Selector = "//tr[1]/td[2]/text()"
+
+	collector := collector{ctx: context.Background(), scrapeConfigs: []types.ScrapeConfig{scrapeConfig, otherScrapeConfig}, globalConfig: testExporterConfig.GlobalConfig}
+
+	expected := `
+# HELP probe_failure_reason Reason of the failure when the scrape of the target did not succeed
+# TYPE probe_failure_reason gauge
+probe_failure_reason{reason="metric",target="foo"} 1
+# HELP probe_success Whether the scrape of the target succeeded
+# TYPE probe_success gauge
+probe_success{target="bar"} 1
+probe_success{target="foo"} 0
+`
+
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "probe_success", "probe_failure_reason")
+	ok(t, err)
+
+	_, _, err = collector.collectScrapeConfig(scrapeConfig)
+	errorContains(t, err, "duplicate sample of metric \"service_requests\" with labels map[svc:foo]")
 }
 
 func TestMakeNewConstMetric(t *testing.T) {
 	value := 123.0
-	_, err := makeNewConstMetric(testExporterConfig, value)
+	_, err := makeNewConstMetric(testExporterConfig.ScrapeConfig.MetricConfig, testExporterConfig.GlobalConfig, value, testExporterConfig.ScrapeConfig.MetricConfig.Labels)
 
 	ok(t, err)
 }
@@ -54,30 +241,43 @@ func TestMakeMetricDesc(t *testing.T) {
 	config := testExporterConfig
 	expected := config.GlobalConfig.MetricNamePrefix + config.ScrapeConfig.MetricConfig.Name
 
-	desc := makeMetricDesc(config)
+	desc := makeMetricDesc(config.ScrapeConfig.MetricConfig, config.GlobalConfig)
 
 	assert(t, strings.Contains(desc.String(), "fqName: \""+expected), "expected metric name to be %s, got %s", expected, desc.String())
 }
 
 func TestMakeNewConstMetric_unsupportedMetricType(t *testing.T) {
 	value := 123.0
-	config := testExporterConfig
-	config.ScrapeConfig.MetricConfig.Type = "summary"
+	metricConfig := testExporterConfig.ScrapeConfig.MetricConfig
+	metricConfig.Type = "summary"
 
-	_, err := makeNewConstMetric(config, value)
+	_, err := makeNewConstMetric(metricConfig, testExporterConfig.GlobalConfig, value, metricConfig.Labels)
 	assert(t, err != nil, "makeNewConstMetric should return an error if the metric type is summary")
 	errorContains(t, err, "not supported")
 }
 
 func TestMakeNewConstMetric_errorCreatingMetric(t *testing.T) {
 	value := 123.0
-	config := testExporterConfig
-	config.ScrapeConfig.MetricConfig.Name = "%invalid metric name!!%"
+	metricConfig := testExporterConfig.ScrapeConfig.MetricConfig
+	metricConfig.Name = "%invalid metric name!!%"
 
-	_, err := makeNewConstMetric(config, value)
+	_, err := makeNewConstMetric(metricConfig, testExporterConfig.GlobalConfig, value, metricConfig.Labels)
 	assert(t, err != nil, "makeNewConstMetric should return an error for an invalid metric")
 }
 
+func TestMakeNewConstMetric_labelSelectors(t *testing.T) {
+	metricConfig := testExporterConfig.ScrapeConfig.MetricConfig
+	metricConfig.LabelSelectors = map[string]string{"service": "../td[1]/text()"}
+
+	labels := map[string]string{"language": "english", "service": "foo"}
+
+	metric, err := makeNewConstMetric(metricConfig, testExporterConfig.GlobalConfig, 1, labels)
+	ok(t, err)
+
+	desc := metric.Desc().String()
+	assert(t, strings.Contains(desc, "variableLabels: [language service]"), "expected desc to contain both static and selector labels, got %s", desc)
+}
+
 func TestGetLabelKeys(t *testing.T) {
 	labels := map[string]string{
 		"foo": "bar",
@@ -104,6 +304,22 @@ func TestGetLabelValues(t *testing.T) {
 	assert(t, compareStringSlices(labelValues, expected), "expected to get the map values %s. got: %s", expected, labelValues)
 }
 
+func TestGetLabelValues_sameOrderAsKeys(t *testing.T) {
+	labels := map[string]string{
+		"foo": "bar",
+		"sun": "rain",
+		"wet": "dry",
+		"abc": "xyz",
+	}
+
+	labelKeys := getLabelKeys(labels)
+	labelValues := getLabelValues(labels)
+
+	for i, k := range labelKeys {
+		equals(t, labels[k], labelValues[i])
+	}
+}
+
 func TestGetPrometheusValueType(t *testing.T) {
 	gaugeMetricType := getPrometheusValueType("gauge")
 
@@ -2,7 +2,10 @@ package main
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
 	"gopkg.in/yaml.v2"
@@ -13,6 +16,9 @@ func getDefaultConfig() types.ExporterConfig {
 		ScrapeConfig: types.ScrapeConfig{
 			DecimalPointSeparator: ".",
 			ThousandsSeparator:    ",",
+			Timeout:               10 * time.Second,
+			Method:                http.MethodGet,
+			MaxRedirects:          10,
 		},
 		GlobalConfig: types.GlobalConfig{
 			MetricNamePrefix: "htmlexporter_",
@@ -37,6 +43,22 @@ func getConfig(configFileArg *os.File) types.ExporterConfig {
 	return config
 }
 
+// loadConfigFile reads and parses the configuration file at `path`, returning errors instead of exiting
+func loadConfigFile(path string) (types.ExporterConfig, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return types.ExporterConfig{}, fmt.Errorf("error opening config file: %s", err)
+	}
+	defer file.Close()
+
+	fileBytes, err := readConfigFile(file)
+	if err != nil {
+		return types.ExporterConfig{}, fmt.Errorf("error reading config file: %s", err)
+	}
+
+	return parseConfig(fileBytes)
+}
+
 func readConfigFile(file *os.File) ([]byte, error) {
 	fileStat, err := file.Stat()
 	if err != nil {
@@ -58,5 +80,272 @@ func parseConfig(config []byte) (types.ExporterConfig, error) {
 		return types.ExporterConfig{}, fmt.Errorf("error parsing supplied YAML configuration file: %s", err.Error())
 	}
 
+	// the single `scrape_config` key is still supported for backwards compatibility,
+	// and is handled as the first entry of the `scrape_configs` list
+	if exporterConfig.ScrapeConfig.Address != "" {
+		exporterConfig.ScrapeConfigs = append([]types.ScrapeConfig{exporterConfig.ScrapeConfig}, exporterConfig.ScrapeConfigs...)
+	}
+
+	defaultScrapeConfig := getDefaultConfig().ScrapeConfig
+	scrapeConfigNames := make(map[string]bool, len(exporterConfig.ScrapeConfigs))
+
+	for i := range exporterConfig.ScrapeConfigs {
+		scrapeConfig := &exporterConfig.ScrapeConfigs[i]
+		applyScrapeConfigDefaults(scrapeConfig, defaultScrapeConfig)
+
+		if err := validateMetricConfigs(*scrapeConfig); err != nil {
+			return types.ExporterConfig{}, err
+		}
+
+		if scrapeConfig.Name == "" {
+			// targets are told apart by name in the probe metrics, so it can only be omitted for a single target
+			if len(exporterConfig.ScrapeConfigs) > 1 {
+				return types.ExporterConfig{}, fmt.Errorf("scrape config %d has no name, which is required when more than one scrape config is set", i)
+			}
+
+			continue
+		}
+
+		if scrapeConfigNames[scrapeConfig.Name] {
+			return types.ExporterConfig{}, fmt.Errorf("duplicate scrape config name \"%s\"", scrapeConfig.Name)
+		}
+
+		scrapeConfigNames[scrapeConfig.Name] = true
+	}
+
+	if err := validateMetricNames(exporterConfig.ScrapeConfigs); err != nil {
+		return types.ExporterConfig{}, err
+	}
+
+	for name, module := range exporterConfig.Modules {
+		// the name is set before validating, so errors refer to the module
+		module.Name = name
+		applyScrapeConfigDefaults(&module, defaultScrapeConfig)
+
+		if err := validateMetricConfigs(module); err != nil {
+			return types.ExporterConfig{}, err
+		}
+
+		if err := validateMetricNames([]types.ScrapeConfig{module}); err != nil {
+			return types.ExporterConfig{}, err
+		}
+
+		exporterConfig.Modules[name] = module
+	}
+
 	return exporterConfig, nil
 }
+
+func applyScrapeConfigDefaults(scrapeConfig *types.ScrapeConfig, defaults types.ScrapeConfig) {
+	if scrapeConfig.DecimalPointSeparator == "" {
+		scrapeConfig.DecimalPointSeparator = defaults.DecimalPointSeparator
+	}
+
+	if scrapeConfig.ThousandsSeparator == "" {
+		scrapeConfig.ThousandsSeparator = defaults.ThousandsSeparator
+	}
+
+	if scrapeConfig.Timeout == 0 {
+		scrapeConfig.Timeout = defaults.Timeout
+	}
+
+	if scrapeConfig.Method == "" {
+		scrapeConfig.Method = defaults.Method
+	}
+
+	if scrapeConfig.MaxRedirects == 0 {
+		scrapeConfig.MaxRedirects = defaults.MaxRedirects
+	}
+}
+
+// validateMetricConfigs checks that the format, selector type and TLS version of a scrape config, and the expressions,
+// transforms, value mappings, locales, units, modes and label characters of its metrics are valid, so that a typo fails at startup instead of on every scrape
+func validateMetricConfigs(scrapeConfig types.ScrapeConfig) error {
+	if !formats[scrapeConfig.Format] {
+		return fmt.Errorf("unknown format \"%s\" in scrape config \"%s\"", scrapeConfig.Format, scrapeConfig.Name)
+	}
+
+	if !selectorTypes[scrapeConfig.SelectorType] {
+		return fmt.Errorf("unknown selector type \"%s\" in scrape config \"%s\"", scrapeConfig.SelectorType, scrapeConfig.Name)
+	}
+
+	if err := validateTLSConfig(scrapeConfig); err != nil {
+		return err
+	}
+
+	for _, metricConfig := range getMetricConfigs(scrapeConfig) {
+		if !selectorTypes[metricConfig.SelectorType] {
+			return fmt.Errorf("unknown selector type \"%s\" in metric \"%s\"", metricConfig.SelectorType, metricConfig.Name)
+		}
+
+		if (scrapeConfig.Format == formatJSON || scrapeConfig.Format == formatXML) && metricConfig.SelectorType == selectorTypeCSS {
+			return fmt.Errorf("metric \"%s\" uses CSS selectors, which are only supported with the html format", metricConfig.Name)
+		}
+
+		if err := validateExtract(scrapeConfig, metricConfig); err != nil {
+			return err
+		}
+
+		if err := validateAggregate(metricConfig); err != nil {
+			return err
+		}
+
+		if (scrapeConfig.Format == formatJSON || scrapeConfig.Format == formatXML) && (metricConfig.Table != nil || metricConfig.Expression != "") {
+			return fmt.Errorf("metric \"%s\" uses a table or expression, which are not supported with the %s format", metricConfig.Name, scrapeConfig.Format)
+		}
+
+		if metricConfig.Locale != "" {
+			if _, found := getNumberLocale(metricConfig.Locale); !found {
+				return fmt.Errorf("unknown locale \"%s\" in metric \"%s\"", metricConfig.Locale, metricConfig.Name)
+			}
+		}
+
+		if err := validateExpression(metricConfig); err != nil {
+			return err
+		}
+
+		if err := validateTransforms(metricConfig); err != nil {
+			return err
+		}
+
+		if err := validateValueMappings(metricConfig); err != nil {
+			return err
+		}
+
+		if !units[metricConfig.Unit] {
+			return fmt.Errorf("unknown unit \"%s\" in metric \"%s\"", metricConfig.Unit, metricConfig.Name)
+		}
+
+		if !metricModes[metricConfig.Mode] {
+			return fmt.Errorf("unknown mode \"%s\" in metric \"%s\"", metricConfig.Mode, metricConfig.Name)
+		}
+
+		// these export a single sample, or take their labels from the table, so they have no element to evaluate label selectors on
+		if len(metricConfig.LabelSelectors) > 0 && (metricConfig.Mode == metricModePresence || metricConfig.Mode == metricModeCount ||
+			metricConfig.Table != nil || metricConfig.Expression != "" || metricConfig.Extract == extractEvaluate) {
+			return fmt.Errorf("metric \"%s\" can't have label selectors, since they can't be combined with the presence and count modes, tables, expressions or evaluate", metricConfig.Name)
+		}
+
+		if metricConfig.LabelAllowedCharacters != "" {
+			if _, err := getLabelDisallowedCharsRegex(metricConfig.LabelAllowedCharacters); err != nil {
+				return fmt.Errorf("invalid label_allowed_characters in metric \"%s\": %s", metricConfig.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateMetricNames checks that metrics sharing a name in `scrapeConfigs`, which are exported together by a probe,
+// have the same help, type and label names, and a static label with different values, so their samples never collide
+func validateMetricNames(scrapeConfigs []types.ScrapeConfig) error {
+	metricConfigsByName := map[string][]types.MetricConfig{}
+	scrapeConfigNamesByName := map[string][]string{}
+
+	for _, scrapeConfig := range scrapeConfigs {
+		for _, metricConfig := range getMetricConfigs(scrapeConfig) {
+			if metricConfig.Name == "" {
+				continue
+			}
+
+			for i, otherMetricConfig := range metricConfigsByName[metricConfig.Name] {
+				if err := validateSharedMetricName(metricConfig, otherMetricConfig); err != nil {
+					return fmt.Errorf("metric \"%s\" of scrape configs \"%s\" and \"%s\" %s", metricConfig.Name, scrapeConfigNamesByName[metricConfig.Name][i], scrapeConfig.Name, err)
+				}
+			}
+
+			metricConfigsByName[metricConfig.Name] = append(metricConfigsByName[metricConfig.Name], metricConfig)
+			scrapeConfigNamesByName[metricConfig.Name] = append(scrapeConfigNamesByName[metricConfig.Name], scrapeConfig.Name)
+		}
+	}
+
+	return nil
+}
+
+func validateSharedMetricName(metricConfig types.MetricConfig, otherMetricConfig types.MetricConfig) error {
+	if metricConfig.Help != otherMetricConfig.Help || metricConfig.Type != otherMetricConfig.Type ||
+		(len(metricConfig.States) > 0) != (len(otherMetricConfig.States) > 0) ||
+		strings.Join(getMetricLabelKeys(metricConfig), ",") != strings.Join(getMetricLabelKeys(otherMetricConfig), ",") {
+		return fmt.Errorf("must have the same help, type and labels")
+	}
+
+	for name, value := range metricConfig.Labels {
+		if otherValue, found := otherMetricConfig.Labels[name]; found && otherValue != value {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("must have a static label with different values to tell their samples apart")
+}
+
+// getScrapeConfigs returns the scrape config named `target`, or every configured scrape config if `target` is empty
+func getScrapeConfigs(config types.ExporterConfig, target string) ([]types.ScrapeConfig, error) {
+	if target == "" {
+		return config.ScrapeConfigs, nil
+	}
+
+	for _, scrapeConfig := range config.ScrapeConfigs {
+		if scrapeConfig.Name == target {
+			return []types.ScrapeConfig{scrapeConfig}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown target \"%s\"", target)
+}
+
+// getModuleScrapeConfig returns the scrape config of the module named `module`, scraping `target` instead of the module address if set
+func getModuleScrapeConfig(config types.ExporterConfig, module string, target string) (types.ScrapeConfig, error) {
+	scrapeConfig, found := config.Modules[module]
+	if !found {
+		return types.ScrapeConfig{}, fmt.Errorf("unknown module \"%s\"", module)
+	}
+
+	if target != "" {
+		scrapeConfig.Address = target
+	}
+
+	if scrapeConfig.Address == "" {
+		return types.ScrapeConfig{}, fmt.Errorf("module \"%s\" has no address configured, a target parameter is required", module)
+	}
+
+	return scrapeConfig, nil
+}
+
+// getMetricConfigs returns the metrics of a scrape config, with selector, selector type, separators and locale inherited
+// from the scrape config when not set in the metric itself
+func getMetricConfigs(scrapeConfig types.ScrapeConfig) []types.MetricConfig {
+	metricConfigs := scrapeConfig.Metrics
+
+	// the single `metric` key is kept for backwards compatibility
+	if len(metricConfigs) == 0 || scrapeConfig.MetricConfig.Name != "" {
+		metricConfigs = append([]types.MetricConfig{scrapeConfig.MetricConfig}, metricConfigs...)
+	}
+
+	inheritedMetricConfigs := make([]types.MetricConfig, len(metricConfigs))
+
+	for i, metricConfig := range metricConfigs {
+		if metricConfig.Selector == "" {
+			metricConfig.Selector = scrapeConfig.Selector
+		}
+
+		if metricConfig.DecimalPointSeparator == "" {
+			metricConfig.DecimalPointSeparator = scrapeConfig.DecimalPointSeparator
+		}
+
+		if metricConfig.ThousandsSeparator == "" {
+			metricConfig.ThousandsSeparator = scrapeConfig.ThousandsSeparator
+		}
+
+		if metricConfig.Locale == "" {
+			metricConfig.Locale = scrapeConfig.Locale
+		}
+
+		if metricConfig.SelectorType == "" {
+			metricConfig.SelectorType = scrapeConfig.SelectorType
+		}
+
+		inheritedMetricConfigs[i] = metricConfig
+	}
+
+	return inheritedMetricConfigs
+}
@@ -6,6 +6,9 @@ import (
 	"os/exec"
 	"path"
 	"testing"
+	"time"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
 )
 
 func openTestFile(t *testing.T, filename string) *os.File {
@@ -42,6 +45,222 @@ func TestParseConfig(t *testing.T) {
 	assert(t, config.ScrapeConfig.MetricConfig.Name == "wikipedia_articles_total", "metric name should be 'wikipedia_articles_total', got: %s", config.ScrapeConfig.MetricConfig.Name)
 }
 
+func TestParseConfig_scrapeConfigs(t *testing.T) {
+	sampleFile := openTestFile(t, "sample-config-multiple-targets.yaml")
+	configFile, err := readConfigFile(sampleFile)
+	ok(t, err)
+
+	config, err := parseConfig(configFile)
+	ok(t, err)
+
+	equals(t, 3, len(config.ScrapeConfigs))
+	equals(t, "wikipedia_articles_total", config.ScrapeConfigs[0].MetricConfig.Name)
+	equals(t, "wikipedia_pages", config.ScrapeConfigs[1].Name)
+	// separators should be set to the default values when omitted
+	equals(t, ",", config.ScrapeConfigs[1].ThousandsSeparator)
+	equals(t, ".", config.ScrapeConfigs[2].ThousandsSeparator)
+}
+
+func TestParseConfig_requestOptions(t *testing.T) {
+	config := []byte(`
+scrape_config:
+  address: "http://foo.dev"
+  timeout: 5s
+  method: POST
+  body: "q=foo"
+  headers:
+    Accept-Language: en
+  follow_redirects: false
+`)
+
+	exporterConfig, err := parseConfig(config)
+	ok(t, err)
+
+	scrapeConfig := exporterConfig.ScrapeConfigs[0]
+	equals(t, 5*time.Second, scrapeConfig.Timeout)
+	equals(t, "POST", scrapeConfig.Method)
+	equals(t, "q=foo", scrapeConfig.Body)
+	equals(t, map[string]string{"Accept-Language": "en"}, scrapeConfig.Headers)
+	equals(t, false, *scrapeConfig.FollowRedirects)
+	// omitted options should be set to the default values
+	equals(t, 10, scrapeConfig.MaxRedirects)
+}
+
+func TestParseConfig_duplicateScrapeConfigName(t *testing.T) {
+	config := []byte(`
+scrape_configs:
+  - name: foo
+    address: "http://foo.dev"
+  - name: foo
+    address: "http://bar.dev"
+`)
+
+	_, err := parseConfig(config)
+	errorContains(t, err, "duplicate scrape config name")
+}
+
+func TestParseConfig_missingScrapeConfigName(t *testing.T) {
+	config := []byte(`
+scrape_configs:
+  - name: foo
+    address: "http://foo.dev"
+  - address: "http://bar.dev"
+`)
+
+	_, err := parseConfig(config)
+	errorContains(t, err, "scrape config 1 has no name")
+}
+
+func TestParseConfig_sharedMetricNames(t *testing.T) {
+	sampleFile := openTestFile(t, "sample-config-multiple-targets.yaml")
+	fileBytes, err := readConfigFile(sampleFile)
+	ok(t, err)
+
+	// wikipedia_articles_total is exported by two targets, told apart by the language label
+	_, err = parseConfig(fileBytes)
+	ok(t, err)
+
+	tests := map[string]string{
+		"must have a static label with different values": `
+scrape_configs:
+  - name: foo
+    address: "http://foo.dev"
+    metric:
+      name: requests_total
+  - name: bar
+    address: "http://bar.dev"
+    metric:
+      name: requests_total
+`,
+		"must have the same help, type and labels": `
+scrape_configs:
+  - name: foo
+    address: "http://foo.dev"
+    metric:
+      name: requests_total
+      labels:
+        site: foo
+  - name: bar
+    address: "http://bar.dev"
+    metric:
+      name: requests_total
+      labels:
+        site: bar
+        env: prod
+`,
+	}
+
+	for expectedError, config := range tests {
+		_, err := parseConfig([]byte(config))
+		errorContains(t, err, "metric \"requests_total\" of scrape configs \"foo\" and \"bar\" "+expectedError)
+	}
+}
+
+func TestGetScrapeConfigs(t *testing.T) {
+	config := types.ExporterConfig{
+		ScrapeConfigs: []types.ScrapeConfig{{Name: "foo"}, {Name: "bar"}},
+	}
+
+	scrapeConfigs, err := getScrapeConfigs(config, "")
+	ok(t, err)
+	equals(t, 2, len(scrapeConfigs))
+
+	scrapeConfigs, err = getScrapeConfigs(config, "bar")
+	ok(t, err)
+	equals(t, []types.ScrapeConfig{{Name: "bar"}}, scrapeConfigs)
+
+	_, err = getScrapeConfigs(config, "foobar")
+	errorContains(t, err, "unknown target")
+}
+
+func TestParseConfig_modules(t *testing.T) {
+	config := []byte(`
+modules:
+  wiki_stats:
+    selector: "//td[@class='mw-statistics-numbers']/text()"
+    thousands_separator: "."
+    metric:
+      name: wikipedia_articles_total
+`)
+
+	exporterConfig, err := parseConfig(config)
+	ok(t, err)
+
+	module := exporterConfig.Modules["wiki_stats"]
+	equals(t, "wiki_stats", module.Name)
+	equals(t, ".", module.ThousandsSeparator)
+	equals(t, ".", module.DecimalPointSeparator)
+}
+
+func TestParseConfig_invalidModule(t *testing.T) {
+	config := []byte(`
+modules:
+  wiki_stats:
+    format: yaml
+    metric:
+      name: wikipedia_articles_total
+`)
+
+	_, err := parseConfig(config)
+	errorContains(t, err, "unknown format \"yaml\" in scrape config \"wiki_stats\"")
+}
+
+func TestGetModuleScrapeConfig(t *testing.T) {
+	config := types.ExporterConfig{
+		Modules: map[string]types.ScrapeConfig{
+			"foo": {Name: "foo", Address: "http://foo.dev"},
+			"bar": {Name: "bar"},
+		},
+	}
+
+	scrapeConfig, err := getModuleScrapeConfig(config, "foo", "")
+	ok(t, err)
+	equals(t, "http://foo.dev", scrapeConfig.Address)
+
+	scrapeConfig, err = getModuleScrapeConfig(config, "foo", "http://bar.dev")
+	ok(t, err)
+	equals(t, "http://bar.dev", scrapeConfig.Address)
+
+	_, err = getModuleScrapeConfig(config, "bar", "")
+	errorContains(t, err, "target parameter is required")
+
+	_, err = getModuleScrapeConfig(config, "foobar", "http://bar.dev")
+	errorContains(t, err, "unknown module")
+}
+
+func TestGetMetricConfigs(t *testing.T) {
+	scrapeConfig := types.ScrapeConfig{
+		Selector:              "//div/text()",
+		DecimalPointSeparator: ".",
+		ThousandsSeparator:    ",",
+		Metrics: []types.MetricConfig{
+			{Name: "foo"},
+			{Name: "bar", Selector: "//span/text()", DecimalPointSeparator: ",", ThousandsSeparator: "."},
+		},
+	}
+
+	metricConfigs := getMetricConfigs(scrapeConfig)
+
+	equals(t, []types.MetricConfig{
+		{Name: "foo", Selector: "//div/text()", DecimalPointSeparator: ".", ThousandsSeparator: ","},
+		{Name: "bar", Selector: "//span/text()", DecimalPointSeparator: ",", ThousandsSeparator: "."},
+	}, metricConfigs)
+}
+
+func TestGetMetricConfigs_singleMetric(t *testing.T) {
+	scrapeConfig := types.ScrapeConfig{
+		Selector:     "//div/text()",
+		MetricConfig: types.MetricConfig{Name: "foo"},
+		Metrics:      []types.MetricConfig{{Name: "bar"}},
+	}
+
+	metricConfigs := getMetricConfigs(scrapeConfig)
+
+	equals(t, 2, len(metricConfigs))
+	equals(t, "foo", metricConfigs[0].Name)
+	equals(t, "//div/text()", metricConfigs[0].Selector)
+}
+
 func TestParseConfig_invalidParameters(t *testing.T) {
 	sampleFile := openTestFile(t, "sample-config-invalid-parameters.yaml")
 	configFile, err := readConfigFile(sampleFile)
@@ -62,6 +281,21 @@ func TestParseConfig_invalidYaml(t *testing.T) {
 	errorContains(t, err, "error parsing supplied YAML")
 }
 
+func TestLoadConfigFile(t *testing.T) {
+	config, err := loadConfigFile(path.Join(getTestDir(t), "sample-config.yaml"))
+	ok(t, err)
+
+	equals(t, "wikipedia_articles_total", config.ScrapeConfig.MetricConfig.Name)
+}
+
+func TestLoadConfigFile_invalidConfig(t *testing.T) {
+	_, err := loadConfigFile(path.Join(getTestDir(t), "sample-config-invalid-syntax.notyaml"))
+	errorContains(t, err, "error parsing supplied YAML")
+
+	_, err = loadConfigFile(path.Join(getTestDir(t), "does-not-exist.yaml"))
+	errorContains(t, err, "error opening config file")
+}
+
 func TestGetConfig(t *testing.T) {
 	sampleFile := openTestFile(t, "sample-config.yaml")
 	config := getConfig(sampleFile)
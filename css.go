@@ -0,0 +1,86 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// types of the selectors of a metric
+const (
+	selectorTypeXPath = "xpath"
+	selectorTypeCSS   = "css"
+)
+
+var selectorTypes = map[string]bool{
+	"":                true,
+	selectorTypeXPath: true,
+	selectorTypeCSS:   true,
+}
+
+// cssPseudoElementRegex matches the pseudo-elements that extract a value from the elements matched by a CSS selector:
+// `::text` for their text, or `::attr(name)` for the value of an attribute
+var cssPseudoElementRegex = regexp.MustCompile(`::(text|attr\(\s*["']?([^"')]+?)["']?\s*\))\s*$`)
+
+// findCSSNodes returns the nodes matched by a CSS selector, which may be none. with a `::text` or `::attr(name)`
+// pseudo-element, text nodes holding the extracted values are returned instead of the matched elements
+func findCSSNodes(doc *html.Node, selector string) ([]*html.Node, error) {
+	query := selector
+	pseudoElement := cssPseudoElementRegex.FindStringSubmatch(selector)
+
+	if pseudoElement != nil {
+		query = strings.TrimSpace(strings.TrimSuffix(selector, pseudoElement[0]))
+	}
+
+	group, err := cascadia.ParseGroup(query)
+	if err != nil {
+		return nil, newScrapeError(failureReasonXPath, "error querying the CSS selector `%s`. error: %s", selector, err)
+	}
+
+	nodes := cascadia.QueryAll(doc, group)
+
+	if pseudoElement == nil {
+		return nodes, nil
+	}
+
+	var values []*html.Node
+
+	for _, node := range nodes {
+		value := getNodeText(node)
+
+		if attribute := pseudoElement[2]; attribute != "" {
+			var found bool
+
+			value, found = getNodeAttribute(node, attribute)
+			if !found {
+				continue
+			}
+		}
+
+		// the text node keeps the element as its parent, which label selectors are evaluated on
+		values = append(values, &html.Node{Type: html.TextNode, Data: value, Parent: node})
+	}
+
+	return values, nil
+}
+
+func getNodeAttribute(node *html.Node, name string) (string, bool) {
+	for _, attribute := range node.Attr {
+		if attribute.Key == name {
+			return attribute.Val, true
+		}
+	}
+
+	return "", false
+}
+
+// getSelectorDescription returns the name of the selector type to be used in messages
+func getSelectorDescription(selectorType string) string {
+	if selectorType == selectorTypeCSS {
+		return "CSS selector"
+	}
+
+	return "XPath expression"
+}
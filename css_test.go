@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+)
+
+const testCSSDocument = `<html><body>
+<div id="stats"><span class="count">1,234</span></div>
+<a class="download" href="/files/42" data-size="512">Download</a>
+<table class="services">
+<tr><td class="name">api</td><td class="latency">12</td></tr>
+<tr><td class="name">db</td><td class="latency">3</td></tr>
+</table>
+</body></html>`
+
+func TestFindCSSNodes(t *testing.T) {
+	doc, err := parseDocument(strings.NewReader(testCSSDocument))
+	ok(t, err)
+
+	tests := []struct {
+		selector string
+		expected []string
+	}{
+		{"#stats .count", []string{"1,234"}},
+		{"#stats .count::text", []string{"1,234"}},
+		{"a.download::attr(href)", []string{"/files/42"}},
+		{"a.download::attr('data-size')", []string{"512"}},
+		{"a.download::attr(title)", nil},
+		{"td.name, td.latency", []string{"api", "12", "db", "3"}},
+		{"table.services tr:nth-child(2) td.latency", []string{"3"}},
+	}
+
+	for _, test := range tests {
+		nodes, err := findCSSNodes(doc, test.selector)
+		ok(t, err)
+
+		var values []string
+		for _, node := range nodes {
+			values = append(values, getNodeText(node))
+		}
+
+		equals(t, test.expected, values)
+	}
+}
+
+func TestFindCSSNodes_invalidSelector(t *testing.T) {
+	doc, err := parseDocument(strings.NewReader(testCSSDocument))
+	ok(t, err)
+
+	_, err = findCSSNodes(doc, "div[")
+	errorContains(t, err, "error querying the CSS selector `div[`")
+	equals(t, failureReasonXPath, getFailureReason(err))
+}
+
+func TestScrape_css(t *testing.T) {
+	server := getTestServer(testCSSDocument)
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.SelectorType = "css"
+	config.Metrics = []types.MetricConfig{
+		{Name: "count", Selector: "#stats .count"},
+		{Name: "download_size", Selector: "a.download::attr(data-size)"},
+		{Name: "services", Selector: "table.services tr", Mode: "count"},
+		{
+			Name:           "service_latency",
+			Selector:       "table.services tr",
+			LabelSelectors: map[string]string{"service": "td.name"},
+			Transforms:     []types.TransformConfig{{Type: "regex_extract", Pattern: `(\d+)\s*$`}},
+		},
+		{Name: "xpath_count", Selector: "//span[@class='count']/text()", SelectorType: "xpath"},
+	}
+
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+	equals(t, 6, len(output))
+
+	equals(t, 1234.0, output[0].value)
+	equals(t, 512.0, output[1].value)
+	equals(t, 2.0, output[2].value)
+	equals(t, 12.0, output[3].value)
+	equals(t, "api", output[3].labels["service"])
+	equals(t, 3.0, output[4].value)
+	equals(t, "db", output[4].labels["service"])
+	equals(t, 1234.0, output[5].value)
+}
+
+func TestScrape_cssPseudoElementLabelSelectors(t *testing.T) {
+	server := getTestServer(`<ul>
+<li data-latency="12"><span class="name">api</span></li>
+<li data-latency="3"><span class="name">db</span></li>
+</ul>`)
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.SelectorType = "css"
+	config.Metrics = []types.MetricConfig{{
+		Name:           "service_latency",
+		Selector:       "li::attr(data-latency)",
+		LabelSelectors: map[string]string{"service": "span.name"},
+	}}
+
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+	equals(t, 2, len(output))
+
+	equals(t, 12.0, output[0].value)
+	equals(t, "api", output[0].labels["service"])
+	equals(t, 3.0, output[1].value)
+	equals(t, "db", output[1].labels["service"])
+}
+
+func TestScrape_cssNoMatch(t *testing.T) {
+	server := getTestServer(testCSSDocument)
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Metrics = []types.MetricConfig{{Name: "foo", Selector: "#missing", SelectorType: "css"}}
+
+	_, _, err := scrape(context.Background(), config)
+	errorContains(t, err, "no elements returned by the CSS selector `#missing`")
+	equals(t, failureReasonXPath, getFailureReason(err))
+}
+
+func TestParseConfig_selectorType(t *testing.T) {
+	config := []byte(`
+scrape_config:
+  address: "http://foo.dev"
+  selector_type: jquery
+`)
+
+	_, err := parseConfig(config)
+	errorContains(t, err, "unknown selector type \"jquery\"")
+
+	config = []byte(`
+scrape_config:
+  address: "http://foo.dev"
+  format: json
+  metrics:
+    - name: foo
+      selector_type: css
+`)
+
+	_, err = parseConfig(config)
+	errorContains(t, err, "only supported with the html format")
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const exporterMetricsNamespace = "htmlexporter"
+
+var (
+	probesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: exporterMetricsNamespace,
+			Name:      "probes_total",
+			Help:      "Total number of scrapes of each target",
+		},
+		[]string{"target"},
+	)
+	probeFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: exporterMetricsNamespace,
+			Name:      "probe_failures_total",
+			Help:      "Total number of failed scrapes of each target, by reason of the failure",
+		},
+		[]string{"target", "reason"},
+	)
+	probeDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: exporterMetricsNamespace,
+			Name:      "probe_duration_seconds",
+			Help:      "Duration of the scrapes of each target",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"target"},
+	)
+	configLastReloadSuccessful = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: exporterMetricsNamespace,
+			Name:      "config_last_reload_successful",
+			Help:      "Whether the last configuration reload attempt was successful",
+		},
+	)
+	configLastReloadSuccessTimestamp = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: exporterMetricsNamespace,
+			Name:      "config_last_reload_success_timestamp_seconds",
+			Help:      "Timestamp of the last successful configuration reload",
+		},
+	)
+)
+
+var exporterMetrics = []prometheus.Collector{
+	probesTotal,
+	probeFailuresTotal,
+	probeDurationSeconds,
+	configLastReloadSuccessful,
+	configLastReloadSuccessTimestamp,
+}
+
+func setConfigReloadStatus(success bool) {
+	if !success {
+		configLastReloadSuccessful.Set(0)
+		return
+	}
+
+	configLastReloadSuccessful.Set(1)
+	configLastReloadSuccessTimestamp.SetToCurrentTime()
+}
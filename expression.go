@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+	"golang.org/x/net/html"
+)
+
+// scrapeExpression evaluates the expression of the metric, with the values of its inputs as variables.
+// `documents` holds the pages already requested in this scrape by address, so each page is requested only once
+func scrapeExpression(ctx context.Context, config types.ScrapeConfig, documents map[string]*html.Node, metricConfig types.MetricConfig, stats *scrapeStats) ([]metricValue, error) {
+	variables := make(map[string]float64, len(metricConfig.Inputs))
+
+	for name, input := range metricConfig.Inputs {
+		value, err := scrapeInput(ctx, config, documents, metricConfig, input, stats)
+		if err != nil {
+			return nil, fmt.Errorf("error scraping input \"%s\". %w", name, err)
+		}
+
+		variables[name] = value
+	}
+
+	value, err := evalExpression(metricConfig.Expression, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	return []metricValue{{metricConfig: metricConfig, value: value, labels: metricConfig.Labels}}, nil
+}
+
+func scrapeInput(ctx context.Context, config types.ScrapeConfig, documents map[string]*html.Node, metricConfig types.MetricConfig, input types.InputConfig, stats *scrapeStats) (float64, error) {
+	address := input.Address
+	if address == "" {
+		address = config.Address
+	}
+
+	doc, found := documents[address]
+	if !found {
+		inputConfig := config
+		inputConfig.Address = address
+
+		body, err := doRequest(ctx, inputConfig, stats)
+		if err != nil {
+			return 0, err
+		}
+
+		doc, err = parseDocument(body)
+		body.Close()
+		if err != nil {
+			return 0, err
+		}
+
+		documents[address] = doc
+	}
+
+	nodes, err := queryNodes(doc, input.Selector, metricConfig.SelectorType)
+	if err != nil {
+		return 0, err
+	}
+
+	thousandsSeparator := input.ThousandsSeparator
+	if thousandsSeparator == "" {
+		thousandsSeparator = metricConfig.ThousandsSeparator
+	}
+
+	decimalPointSeparator := input.DecimalPointSeparator
+	if decimalPointSeparator == "" {
+		decimalPointSeparator = metricConfig.DecimalPointSeparator
+	}
+
+	parseNumber := getNumberParser(metricConfig.Locale, thousandsSeparator, decimalPointSeparator)
+
+	return parseNumber(getFirstNodeValue(nodes))
+}
+
+// validateExpression parses the expression of a metric, checking that it only uses the supported operators
+// and that every variable in it is one of the inputs of the metric
+func validateExpression(metricConfig types.MetricConfig) error {
+	if metricConfig.Expression == "" {
+		return nil
+	}
+
+	expr, err := parser.ParseExpr(metricConfig.Expression)
+	if err != nil {
+		return fmt.Errorf("error parsing expression `%s` of metric \"%s\": %s", metricConfig.Expression, metricConfig.Name, err)
+	}
+
+	err = validateExpressionNode(expr, metricConfig.Inputs)
+	if err != nil {
+		return fmt.Errorf("invalid expression `%s` of metric \"%s\": %s", metricConfig.Expression, metricConfig.Name, err)
+	}
+
+	return nil
+}
+
+func validateExpressionNode(node ast.Expr, inputs map[string]types.InputConfig) error {
+	switch n := node.(type) {
+	case *ast.BasicLit:
+		if n.Kind != token.INT && n.Kind != token.FLOAT {
+			return fmt.Errorf("unsupported literal %s", n.Value)
+		}
+
+		return nil
+	case *ast.Ident:
+		if _, found := inputs[n.Name]; !found {
+			return fmt.Errorf("\"%s\" is not an input of the metric", n.Name)
+		}
+
+		return nil
+	case *ast.ParenExpr:
+		return validateExpressionNode(n.X, inputs)
+	case *ast.UnaryExpr:
+		if n.Op == token.SUB || n.Op == token.ADD {
+			return validateExpressionNode(n.X, inputs)
+		}
+	case *ast.BinaryExpr:
+		if n.Op == token.ADD || n.Op == token.SUB || n.Op == token.MUL || n.Op == token.QUO {
+			if err := validateExpressionNode(n.X, inputs); err != nil {
+				return err
+			}
+
+			return validateExpressionNode(n.Y, inputs)
+		}
+	}
+
+	return fmt.Errorf("unsupported operation")
+}
+
+// evalExpression evaluates an arithmetic expression with the +, -, * and / operators and parentheses
+func evalExpression(expression string, variables map[string]float64) (float64, error) {
+	expr, err := parser.ParseExpr(expression)
+	if err != nil {
+		return 0, newScrapeError(failureReasonExpression, "error parsing expression `%s`. error: %s", expression, err)
+	}
+
+	return evalExpressionNode(expr, variables)
+}
+
+func evalExpressionNode(node ast.Expr, variables map[string]float64) (float64, error) {
+	switch n := node.(type) {
+	case *ast.BasicLit:
+		if n.Kind != token.INT && n.Kind != token.FLOAT {
+			return 0, newScrapeError(failureReasonExpression, "unsupported literal %s in expression", n.Value)
+		}
+
+		value, err := strconv.ParseFloat(n.Value, 64)
+		if err != nil {
+			return 0, newScrapeError(failureReasonExpression, "invalid number %s in expression. error: %s", n.Value, err)
+		}
+
+		return value, nil
+	case *ast.Ident:
+		value, found := variables[n.Name]
+		if !found {
+			return 0, newScrapeError(failureReasonExpression, "missing input \"%s\" in expression", n.Name)
+		}
+
+		return value, nil
+	case *ast.ParenExpr:
+		return evalExpressionNode(n.X, variables)
+	case *ast.UnaryExpr:
+		value, err := evalExpressionNode(n.X, variables)
+		if err != nil {
+			return 0, err
+		}
+
+		switch n.Op {
+		case token.SUB:
+			return -value, nil
+		case token.ADD:
+			return value, nil
+		}
+	case *ast.BinaryExpr:
+		left, err := evalExpressionNode(n.X, variables)
+		if err != nil {
+			return 0, err
+		}
+
+		right, err := evalExpressionNode(n.Y, variables)
+		if err != nil {
+			return 0, err
+		}
+
+		switch n.Op {
+		case token.ADD:
+			return left + right, nil
+		case token.SUB:
+			return left - right, nil
+		case token.MUL:
+			return left * right, nil
+		case token.QUO:
+			if right == 0 {
+				return 0, newScrapeError(failureReasonExpression, "division by zero in expression")
+			}
+
+			return left / right, nil
+		}
+	}
+
+	return 0, newScrapeError(failureReasonExpression, "unsupported operation in expression")
+}
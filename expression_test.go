@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+)
+
+func TestEvalExpression(t *testing.T) {
+	variables := map[string]float64{"a": 3, "b": 4}
+
+	tests := map[string]float64{
+		"(a / b) * 100": 75,
+		"a + b * 2":     11,
+		"-a + 1.5":      -1.5,
+		"(a - b) / 2":   -0.5,
+	}
+
+	for expression, expected := range tests {
+		value, err := evalExpression(expression, variables)
+		ok(t, err)
+		assert(t, value == expected, "expected expression %s to equal %f, got %f", expression, expected, value)
+	}
+}
+
+func TestEvalExpression_errors(t *testing.T) {
+	variables := map[string]float64{"a": 3, "b": 0}
+
+	tests := map[string]string{
+		"a / b":        "division by zero",
+		"a + c":        "missing input \"c\"",
+		"a +":          "error parsing expression",
+		"a % b":        "unsupported operation",
+		"len(a)":       "unsupported operation",
+		"a + \"text\"": "unsupported literal",
+	}
+
+	for expression, expectedError := range tests {
+		_, err := evalExpression(expression, variables)
+		assert(t, err != nil, "expected expression %s to fail", expression)
+		errorContains(t, err, expectedError)
+		equals(t, failureReasonExpression, getFailureReason(err))
+	}
+}
+
+func TestScrape_expression(t *testing.T) {
+	requests := map[string]int{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		fmt.Fprintln(w, "<div id=\"used\">1,500</div><div id=\"total\">2,000</div>")
+	})
+	mux.HandleFunc("/other", func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		fmt.Fprintln(w, "<div id=\"factor\">0,5</div>")
+	})
+	server := httptest.NewServer(mux)
+
+	config := testScrapeConfig
+	config.Address = server.URL + "/"
+	config.Metrics = []types.MetricConfig{{
+		Name:       "usage_ratio",
+		Expression: "used / total * factor",
+		Inputs: map[string]types.InputConfig{
+			"used":   {Selector: "//div[@id='used']/text()"},
+			"total":  {Selector: "//div[@id='total']/text()"},
+			"factor": {Address: server.URL + "/other", Selector: "//div[@id='factor']/text()", DecimalPointSeparator: ",", ThousandsSeparator: "."},
+		},
+	}}
+
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+
+	equals(t, 0.375, output[0].value)
+	equals(t, map[string]int{"/": 1, "/other": 1}, requests)
+}
+
+func TestScrape_expressionDivisionByZero(t *testing.T) {
+	server := getTestServer("<div id=\"foobar\">0</div>")
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Metrics = []types.MetricConfig{{
+		Name:       "ratio",
+		Expression: "a / b",
+		Inputs: map[string]types.InputConfig{
+			"a": {Selector: "//div/text()"},
+			"b": {Selector: "//div/text()"},
+		},
+	}}
+
+	_, _, err := scrape(context.Background(), config)
+	errorContains(t, err, "division by zero")
+	equals(t, failureReasonExpression, getFailureReason(err))
+}
+
+func TestValidateExpression(t *testing.T) {
+	inputs := map[string]types.InputConfig{"a": {Selector: "//a"}, "b": {Selector: "//b"}}
+
+	err := validateExpression(types.MetricConfig{Name: "foo", Expression: "(a - b) / 2", Inputs: inputs})
+	ok(t, err)
+
+	tests := map[string]string{
+		"a + c":        "\"c\" is not an input of the metric",
+		"a +":          "error parsing expression `a +` of metric \"foo\"",
+		"a % b":        "unsupported operation",
+		"len(a)":       "unsupported operation",
+		"a + \"text\"": "unsupported literal",
+	}
+
+	for expression, expectedError := range tests {
+		err := validateExpression(types.MetricConfig{Name: "foo", Expression: expression, Inputs: inputs})
+		errorContains(t, err, expectedError)
+	}
+}
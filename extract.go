@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+)
+
+// extraction modes, defining which part of a matched element is used as the value of a metric
+const (
+	// extractText is the text of the element and its descendants, with whitespace normalized
+	extractText = "text"
+	// extractAttribute is the value of the attribute named by the `attribute` of the metric
+	extractAttribute = "attribute"
+	// extractHTML is the HTML of the element, including its own tags
+	extractHTML = "html"
+	// extractEvaluate evaluates the selector as an XPath expression that returns a number, string or boolean,
+	// e.g. `count(//li)` or `sum(//td[@class='size'])`
+	extractEvaluate = "evaluate"
+)
+
+var extractModes = map[string]bool{
+	"":               true,
+	extractText:      true,
+	extractAttribute: true,
+	extractHTML:      true,
+	extractEvaluate:  true,
+}
+
+func validateExtract(scrapeConfig types.ScrapeConfig, metricConfig types.MetricConfig) error {
+	if metricConfig.Extract == "" {
+		return nil
+	}
+
+	if !extractModes[metricConfig.Extract] {
+		return fmt.Errorf("unknown extract \"%s\" in metric \"%s\"", metricConfig.Extract, metricConfig.Name)
+	}
+
+	if scrapeConfig.Format != "" && scrapeConfig.Format != formatHTML {
+		return fmt.Errorf("metric \"%s\" sets extract, which is only supported with the html format", metricConfig.Name)
+	}
+
+	if metricConfig.Extract == extractAttribute && metricConfig.Attribute == "" {
+		return fmt.Errorf("metric \"%s\" extracts an attribute, but has no attribute set", metricConfig.Name)
+	}
+
+	if metricConfig.Extract == extractEvaluate && metricConfig.SelectorType == selectorTypeCSS {
+		return fmt.Errorf("metric \"%s\" evaluates its selector, which requires an XPath selector", metricConfig.Name)
+	}
+
+	return nil
+}
+
+// getMetricNodeValue returns the value of a matched node to be parsed, according to the extraction mode of the metric.
+// by default, it's the text of the node
+func getMetricNodeValue(node *html.Node, metricConfig types.MetricConfig) (string, error) {
+	switch metricConfig.Extract {
+	case extractText:
+		return strings.Join(strings.Fields(getNodeText(node)), " "), nil
+	case extractAttribute:
+		value, found := getNodeAttribute(node, metricConfig.Attribute)
+		if !found {
+			return "", newScrapeError(failureReasonXPath, "element <%s> matched by the selector `%s` has no attribute \"%s\"", node.Data, metricConfig.Selector, metricConfig.Attribute)
+		}
+
+		return value, nil
+	case extractHTML:
+		return htmlquery.OutputHTML(node, true), nil
+	}
+
+	return getNodeText(node), nil
+}
+
+// scrapeXPathEvaluation exports the result of evaluating the selector of the metric as an XPath expression.
+// numbers and booleans are exported as they are, strings are parsed like any other value, and node sets
+// are parsed from the text of their first node
+func scrapeXPathEvaluation(doc *html.Node, metricConfig types.MetricConfig, stats *scrapeStats) ([]metricValue, error) {
+	expr, err := xpath.Compile(metricConfig.Selector)
+	if err != nil {
+		return nil, newScrapeError(failureReasonXPath, "error querying the XPath expression `%s`. error: %s", metricConfig.Selector, err)
+	}
+
+	var value string
+	valueMetricConfig := metricConfig
+
+	switch result := expr.Evaluate(htmlquery.CreateXPathNavigator(doc)).(type) {
+	case float64:
+		value = strconv.FormatFloat(result, 'f', -1, 64)
+		valueMetricConfig = withPlainNumberFormat(metricConfig)
+	case bool:
+		value = "0"
+		if result {
+			value = "1"
+		}
+
+		valueMetricConfig = withPlainNumberFormat(metricConfig)
+	case string:
+		value = result
+	case *xpath.NodeIterator:
+		if !result.MoveNext() {
+			return nil, newScrapeError(failureReasonXPath, "no elements returned by the XPath expression `%s`", metricConfig.Selector)
+		}
+
+		value = result.Current().Value()
+	}
+
+	stats.matchedNodes[metricConfig.Name] = 1
+
+	values, err := makeMetricValues(value, valueMetricConfig, metricConfig.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	// the samples keep the original metric config, so they match its description
+	for i := range values {
+		values[i].metricConfig = metricConfig
+	}
+
+	return values, nil
+}
+
+// withPlainNumberFormat returns the metric config set to parse numbers with a decimal point and no locale,
+// for values that are already numbers, like the ones in JSON documents or returned by XPath functions
+func withPlainNumberFormat(metricConfig types.MetricConfig) types.MetricConfig {
+	metricConfig.Locale = ""
+	metricConfig.DecimalPointSeparator = "."
+	metricConfig.ThousandsSeparator = ","
+
+	return metricConfig
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+)
+
+const testExtractDocument = `<html><body>
+<div id="users">Active
+  users: <b>1,234</b>
+</div>
+<a id="download" href="/files/42" data-size="512">Download</a>
+<ul><li>a</li><li>b</li><li>c</li></ul>
+<table><tr><td class="size">10</td><td class="size">2.5</td></tr></table>
+<span id="status" title="Up">status</span>
+</body></html>`
+
+func TestScrape_extract(t *testing.T) {
+	server := getTestServer(testExtractDocument)
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Metrics = []types.MetricConfig{
+		{Name: "users", Selector: "//div[@id='users']", Extract: "text", Transforms: []types.TransformConfig{{Type: "strip_prefix", Value: "Active users: "}}},
+		{Name: "download_size", Selector: "//a[@id='download']", Extract: "attribute", Attribute: "data-size"},
+		{Name: "download_html_length", Selector: "//a[@id='download']", Extract: "html", Mode: "length"},
+		{Name: "items", Selector: "count(//li)", Extract: "evaluate"},
+		{Name: "total_size", Selector: "sum(//td[@class='size'])", Extract: "evaluate"},
+		{Name: "has_items", Selector: "count(//li) > 2", Extract: "evaluate"},
+		{Name: "first_size", Selector: "string(//td[@class='size'][2])", Extract: "evaluate"},
+		{Name: "status", Selector: "//span[@id='status']/@title", Extract: "evaluate", ValueMappings: []types.ValueMappingConfig{{Match: "Up", Value: 1}}},
+	}
+
+	output, stats, err := scrape(context.Background(), config)
+	ok(t, err)
+
+	expected := []float64{1234, 512, 62, 3, 12.5, 1, 2.5, 1}
+	equals(t, len(expected), len(output))
+
+	for i, value := range expected {
+		equals(t, value, output[i].value)
+	}
+
+	equals(t, 1, stats.matchedNodes["items"])
+}
+
+func TestScrape_extractAttributeLabel(t *testing.T) {
+	server := getTestServer(testExtractDocument)
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Metrics = []types.MetricConfig{{
+		Name:           "download_size",
+		Selector:       "//a[@id='download']",
+		Extract:        "attribute",
+		Attribute:      "data-size",
+		LabelSelectors: map[string]string{"href": "./@href"},
+	}}
+
+	// attribute nodes matched by label selectors have their value as the label, instead of their name
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+	equals(t, 512.0, output[0].value)
+	equals(t, "/files/42", output[0].labels["href"])
+}
+
+func TestScrape_extractEvaluateLocale(t *testing.T) {
+	server := getTestServer(testExtractDocument)
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Locale = "pt-BR"
+	config.Metrics = []types.MetricConfig{{Name: "total_size", Selector: "sum(//td[@class='size'])", Extract: "evaluate"}}
+
+	// numbers returned by XPath functions always use a decimal point
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+	equals(t, 12.5, output[0].value)
+}
+
+func TestScrape_extractErrors(t *testing.T) {
+	tests := []struct {
+		metricConfig  types.MetricConfig
+		expectedError string
+	}{
+		{types.MetricConfig{Name: "foo", Selector: "//a[@id='download']", Extract: "attribute", Attribute: "title"}, "element <a> matched by the selector `//a[@id='download']` has no attribute \"title\""},
+		{types.MetricConfig{Name: "foo", Selector: "count(//li", Extract: "evaluate"}, "error querying the XPath expression"},
+		{types.MetricConfig{Name: "foo", Selector: "//missing", Extract: "evaluate"}, "no elements returned by the XPath expression"},
+	}
+
+	server := getTestServer(testExtractDocument)
+
+	for _, test := range tests {
+		config := testScrapeConfig
+		config.Address = server.URL
+		config.Metrics = []types.MetricConfig{test.metricConfig}
+
+		_, _, err := scrape(context.Background(), config)
+		errorContains(t, err, test.expectedError)
+		equals(t, failureReasonXPath, getFailureReason(err))
+	}
+}
+
+func TestParseConfig_extract(t *testing.T) {
+	tests := []struct {
+		metric        string
+		expectedError string
+	}{
+		{"extract: inner_text", "unknown extract \"inner_text\""},
+		{"extract: attribute", "has no attribute set"},
+		{"extract: evaluate\n      selector_type: css", "requires an XPath selector"},
+	}
+
+	for _, test := range tests {
+		config := []byte(`
+scrape_config:
+  address: "http://foo.dev"
+  metrics:
+    - name: foo
+      ` + test.metric + `
+`)
+
+		_, err := parseConfig(config)
+		errorContains(t, err, test.expectedError)
+	}
+}
@@ -3,15 +3,20 @@ module github.com/GusAntoniassi/prometheus-html-exporter
 go 1.17
 
 require (
+	github.com/PaesslerAG/gval v1.0.0
+	github.com/PaesslerAG/jsonpath v0.1.1
 	github.com/akamensky/argparse v1.3.1
+	github.com/andybalholm/cascadia v1.3.1
 	github.com/antchfx/htmlquery v1.2.4
+	github.com/antchfx/xmlquery v1.3.9
+	github.com/antchfx/xpath v1.2.4
 	github.com/prometheus/client_golang v1.11.0
 	github.com/sirupsen/logrus v1.8.1
+	golang.org/x/net v0.0.0-20210916014120-12bc252f5db8
 	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
-	github.com/antchfx/xpath v1.2.0 // indirect
 	github.com/beorn7/perks v1.0.1 // indirect
 	github.com/cespare/xxhash/v2 v2.1.1 // indirect
 	github.com/golang/groupcache v0.0.0-20200121045136-8c9f03a8e57e // indirect
@@ -20,7 +25,6 @@ require (
 	github.com/prometheus/client_model v0.2.0 // indirect
 	github.com/prometheus/common v0.26.0 // indirect
 	github.com/prometheus/procfs v0.6.0 // indirect
-	golang.org/x/net v0.0.0-20210805182204-aaa1db679c0d // indirect
 	golang.org/x/sys v0.0.0-20210809222454-d867a43fc93e // indirect
 	golang.org/x/text v0.3.6 // indirect
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
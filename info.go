@@ -0,0 +1,50 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+)
+
+// metricTypeInfo is the type of metrics that are always 1, exporting scraped text in their labels,
+// e.g. the version of the software of the target. they are exposed as gauges
+const metricTypeInfo = "info"
+
+// defaultInfoLabelMaxLength limits the length of scraped label values of info metrics, which would otherwise
+// create a new series whenever a long or changing text, like a timestamp, is captured
+const defaultInfoLabelMaxLength = 128
+
+// sanitizeLabelValue normalizes the whitespace of a scraped label value, then replaces the characters not in
+// `label_allowed_characters` with underscores and truncates it to `label_max_length` characters
+func sanitizeLabelValue(value string, metricConfig types.MetricConfig) (string, error) {
+	value = strings.Join(strings.Fields(value), " ")
+
+	if metricConfig.LabelAllowedCharacters != "" {
+		disallowedChars, err := getLabelDisallowedCharsRegex(metricConfig.LabelAllowedCharacters)
+		if err != nil {
+			return "", newScrapeError(failureReasonMetric, "invalid label_allowed_characters `%s`. error: %s", metricConfig.LabelAllowedCharacters, err)
+		}
+
+		value = disallowedChars.ReplaceAllString(value, "_")
+	}
+
+	maxLength := metricConfig.LabelMaxLength
+	if maxLength == 0 && metricConfig.Type == metricTypeInfo {
+		maxLength = defaultInfoLabelMaxLength
+	}
+
+	if maxLength > 0 {
+		if runes := []rune(value); len(runes) > maxLength {
+			value = string(runes[:maxLength])
+		}
+	}
+
+	return value, nil
+}
+
+// getLabelDisallowedCharsRegex returns a regular expression matching any character not in `allowedChars`,
+// which is written like the contents of a regular expression character class, e.g. "a-zA-Z0-9._-"
+func getLabelDisallowedCharsRegex(allowedChars string) (*regexp.Regexp, error) {
+	return regexp.Compile("[^" + allowedChars + "]")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSanitizeLabelValue(t *testing.T) {
+	tests := []struct {
+		value        string
+		metricConfig types.MetricConfig
+		expected     string
+	}{
+		{"  v1.2.3\n", types.MetricConfig{}, "v1.2.3"},
+		{"Build  of\n\t2024", types.MetricConfig{}, "Build of 2024"},
+		{"v1.2.3 (beta)", types.MetricConfig{LabelAllowedCharacters: "a-zA-Z0-9._-"}, "v1.2.3__beta_"},
+		{"abcdef", types.MetricConfig{LabelMaxLength: 3}, "abc"},
+		{"äöüß", types.MetricConfig{LabelMaxLength: 2}, "äö"},
+		{strings.Repeat("a", 200), types.MetricConfig{Type: "info"}, strings.Repeat("a", defaultInfoLabelMaxLength)},
+		{strings.Repeat("a", 200), types.MetricConfig{}, strings.Repeat("a", 200)},
+	}
+
+	for _, test := range tests {
+		value, err := sanitizeLabelValue(test.value, test.metricConfig)
+		ok(t, err)
+		equals(t, test.expected, value)
+	}
+}
+
+func TestCollect_infoMetric(t *testing.T) {
+	server := getTestServer(`<footer><span class="version">Version 2.4.1 (build a1b2c3d)</span><span class="region">eu-west-1</span></footer>`)
+
+	scrapeConfig := testScrapeConfig
+	scrapeConfig.Address = server.URL
+	scrapeConfig.Metrics = []types.MetricConfig{{
+		Name:     "app_info",
+		Help:     "Information about the application",
+		Type:     "info",
+		Selector: "//footer",
+		Labels:   map[string]string{"env": "prod"},
+		LabelSelectors: map[string]string{
+			"version": ".//span[@class='version']/text()",
+			"region":  ".//span[@class='region']/text()",
+		},
+		LabelAllowedCharacters: "a-z0-9.-",
+		LabelMaxLength:         13,
+	}}
+
+	collector := collector{ctx: context.Background(), scrapeConfigs: []types.ScrapeConfig{scrapeConfig}, globalConfig: testExporterConfig.GlobalConfig}
+
+	expected := `
+# HELP htmlexporter_app_info Information about the application
+# TYPE htmlexporter_app_info gauge
+htmlexporter_app_info{env="prod",region="eu-west-1",version="_ersion_2.4.1"} 1
+`
+
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "htmlexporter_app_info")
+	ok(t, err)
+}
+
+func TestParseConfig_invalidLabelAllowedCharacters(t *testing.T) {
+	config := []byte(`
+scrape_config:
+  address: "http://foo.dev"
+  metrics:
+    - name: foo
+      type: info
+      label_allowed_characters: "z-a"
+`)
+
+	_, err := parseConfig(config)
+	errorContains(t, err, "invalid label_allowed_characters in metric \"foo\"")
+}
@@ -1,8 +1,12 @@
 package types
 
+import "time"
+
 type ExporterConfig struct {
-	ScrapeConfig ScrapeConfig `yaml:"scrape_config"`
-	GlobalConfig GlobalConfig `yaml:"global_config"`
+	ScrapeConfig  ScrapeConfig            `yaml:"scrape_config"`
+	ScrapeConfigs []ScrapeConfig          `yaml:"scrape_configs"`
+	Modules       map[string]ScrapeConfig `yaml:"modules"`
+	GlobalConfig  GlobalConfig            `yaml:"global_config"`
 }
 
 type GlobalConfig struct {
@@ -14,14 +18,125 @@ type ScrapeConfig struct {
 	Name                  string `yaml:",omitempty"`
 	Address               string
 	Selector              string
-	DecimalPointSeparator string       `yaml:"decimal_point_separator"`
-	ThousandsSeparator    string       `yaml:"thousands_separator"`
-	MetricConfig          MetricConfig `yaml:"metric"`
+	SelectorType          string `yaml:"selector_type"`
+	Format                string
+	Namespaces            map[string]string
+	DecimalPointSeparator string `yaml:"decimal_point_separator"`
+	ThousandsSeparator    string `yaml:"thousands_separator"`
+	Locale                string
+	MetricConfig          MetricConfig   `yaml:"metric"`
+	Metrics               []MetricConfig `yaml:"metrics"`
+	Timeout               time.Duration
+	Headers               map[string]string
+	Method                string
+	Body                  string
+	MaxRedirects          int         `yaml:"max_redirects"`
+	FollowRedirects       *bool       `yaml:"follow_redirects"`
+	BasicAuth             *BasicAuth  `yaml:"basic_auth"`
+	BearerToken           string      `yaml:"bearer_token"`
+	BearerTokenFile       string      `yaml:"bearer_token_file"`
+	BearerTokenEnv        string      `yaml:"bearer_token_env"`
+	HeaderAuth            *HeaderAuth `yaml:"header_auth"`
+	TLSConfig             *TLSConfig  `yaml:"tls_config"`
+	Login                 *LoginConfig
+	Steps                 []StepConfig
+}
+
+type StepConfig struct {
+	Address   string
+	Headers   map[string]string
+	Variables map[string]string
+}
+
+type LoginConfig struct {
+	Address         string
+	Method          string
+	Form            map[string]string
+	FormFiles       map[string]string `yaml:"form_files"`
+	FormEnv         map[string]string `yaml:"form_env"`
+	SuccessSelector string            `yaml:"success_selector"`
+}
+
+type TLSConfig struct {
+	CAFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	ServerName         string `yaml:"server_name"`
+	MinVersion         string `yaml:"min_version"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+type BasicAuth struct {
+	Username     string
+	Password     string
+	PasswordFile string `yaml:"password_file"`
+	PasswordEnv  string `yaml:"password_env"`
+}
+
+type HeaderAuth struct {
+	Name      string
+	Value     string
+	ValueFile string `yaml:"value_file"`
+	ValueEnv  string `yaml:"value_env"`
 }
 
 type MetricConfig struct {
-	Name   string
-	Help   string
-	Type   string
-	Labels map[string]string
+	Name                   string
+	Help                   string
+	Type                   string
+	Labels                 map[string]string
+	Selector               string
+	ValueSelector          string `yaml:"value_selector"`
+	SelectorType           string `yaml:"selector_type"`
+	Extract                string
+	Attribute              string
+	Aggregate              string
+	SkipInvalidValues      bool `yaml:"skip_invalid_values"`
+	Mode                   string
+	DecimalPointSeparator  string            `yaml:"decimal_point_separator"`
+	ThousandsSeparator     string            `yaml:"thousands_separator"`
+	LabelSelectors         map[string]string `yaml:"label_selectors"`
+	LabelMaxLength         int               `yaml:"label_max_length"`
+	LabelAllowedCharacters string            `yaml:"label_allowed_characters"`
+	Table                  *TableConfig
+	Expression             string
+	Inputs                 map[string]InputConfig
+	Transforms             []TransformConfig
+	Unit                   string
+	Locale                 string
+	ValueMappings          []ValueMappingConfig `yaml:"value_mappings"`
+	DefaultValue           *float64             `yaml:"default_value"`
+	States                 []StateConfig
+}
+
+type ValueMappingConfig struct {
+	Match   string
+	Pattern string
+	Value   float64
+}
+
+type StateConfig struct {
+	Name    string
+	Match   string
+	Pattern string
+}
+
+type TransformConfig struct {
+	Type        string
+	Pattern     string
+	Group       int
+	Replacement string
+	Value       string
+}
+
+type InputConfig struct {
+	Address               string
+	Selector              string
+	DecimalPointSeparator string `yaml:"decimal_point_separator"`
+	ThousandsSeparator    string `yaml:"thousands_separator"`
+}
+
+type TableConfig struct {
+	LabelColumns map[string]string `yaml:"label_columns"`
+	ValueColumns []string          `yaml:"value_columns"`
 }
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+	"github.com/PaesslerAG/gval"
+	"github.com/PaesslerAG/jsonpath"
+	log "github.com/sirupsen/logrus"
+)
+
+// formats of the response body of a scrape config
+const (
+	formatHTML = "html"
+	formatJSON = "json"
+	formatXML  = "xml"
+)
+
+var formats = map[string]bool{
+	"":         true,
+	formatHTML: true,
+	formatJSON: true,
+	formatXML:  true,
+}
+
+// jsonPathLanguage evaluates JSONPath expressions, with the comparison and arithmetic operators of gval in filters
+var jsonPathLanguage = gval.Full(jsonpath.Language())
+
+// scrapeJSON returns the metric values of a JSON response body, selected by JSONPath expressions
+func scrapeJSON(body []byte, config types.ScrapeConfig, stats *scrapeStats) ([]metricValue, error) {
+	start := time.Now()
+	doc, err := parseJSONDocument(body)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.addPhaseDuration(phaseParse, time.Since(start))
+
+	var metricValues []metricValue
+
+	for _, metricConfig := range getMetricConfigs(config) {
+		log.Debugf("scraping value from requested URL with JSONPath selector '%s'", metricConfig.Selector)
+
+		start = time.Now()
+		values, err := scrapeNodeMetric(jsonNode{value: doc}, metricConfig, stats)
+		stats.addPhaseDuration(phaseXPath, time.Since(start))
+
+		if err != nil {
+			return nil, err
+		}
+
+		log.Debugf("scraped %d value(s) for metric '%s' from URL '%s'", len(values), metricConfig.Name, redactURL(config.Address))
+		metricValues = append(metricValues, values...)
+	}
+
+	return metricValues, nil
+}
+
+func parseJSONDocument(body []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// numbers are kept as text, so they go through the same parsing as the values of HTML pages
+	decoder.UseNumber()
+
+	var doc interface{}
+
+	err := decoder.Decode(&doc)
+	if err != nil {
+		return nil, newScrapeError(failureReasonParse, "error parsing the response body as JSON. error: %s", err)
+	}
+
+	return doc, nil
+}
+
+// jsonNode is a value of a JSON document, queried with JSONPath expressions
+type jsonNode struct {
+	value interface{}
+}
+
+func (n jsonNode) query(selector string) ([]metricNode, error) {
+	values, err := queryJSON(n.value, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]metricNode, len(values))
+	for i, value := range values {
+		results[i] = jsonNode{value: value}
+	}
+
+	return results, nil
+}
+
+func (n jsonNode) text() (string, error) {
+	return getJSONValueText(n.value)
+}
+
+func (n jsonNode) metricValues(metricConfig types.MetricConfig, labels map[string]string) ([]metricValue, error) {
+	return makeJSONMetricValues(n.value, metricConfig, labels)
+}
+
+func (n jsonNode) selectorDescription() string {
+	return "JSONPath expression"
+}
+
+func makeJSONMetricValues(value interface{}, metricConfig types.MetricConfig, labels map[string]string) ([]metricValue, error) {
+	text, err := getJSONValueText(value)
+	if err != nil {
+		return nil, err
+	}
+
+	// booleans are exported as 1 and 0, unless they are mapped to other values
+	if boolean, isBool := value.(bool); isBool && len(metricConfig.ValueMappings) == 0 && len(metricConfig.States) == 0 {
+		text = "0"
+		if boolean {
+			text = "1"
+		}
+	}
+
+	// JSON numbers always use a decimal point, whatever the locale and separators of the metric
+	valueMetricConfig := metricConfig
+	switch value.(type) {
+	case json.Number, bool:
+		valueMetricConfig = withPlainNumberFormat(metricConfig)
+	}
+
+	values, err := makeMetricValues(text, valueMetricConfig, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	// the samples keep the original metric config, so they match its description
+	for i := range values {
+		values[i].metricConfig = metricConfig
+	}
+
+	return values, nil
+}
+
+// queryJSON returns the values matched by a JSONPath expression, e.g. `$.services[*].latency`, evaluated relative
+// to `doc`. an expression matching an array returns its elements, and one matching nothing returns no values
+func queryJSON(doc interface{}, expression string) ([]interface{}, error) {
+	path, err := jsonPathLanguage.NewEvaluable(normalizeJSONPath(expression))
+	if err != nil {
+		return nil, newScrapeError(failureReasonXPath, "error parsing the JSONPath expression `%s`. error: %s", expression, err)
+	}
+
+	result, err := path(context.Background(), doc)
+	if err != nil {
+		// e.g. a missing key or an index out of bounds
+		log.Debugf("JSONPath expression `%s` matched nothing: %s", expression, err)
+		return nil, nil
+	}
+
+	if results, isArray := result.([]interface{}); isArray {
+		return results, nil
+	}
+
+	return []interface{}{result}, nil
+}
+
+// normalizeJSONPath turns relative and jq-style paths, e.g. `@.name` or `.services[].name`, into JSONPath
+// expressions starting at `$`, which is the value the expression is evaluated on
+func normalizeJSONPath(expression string) string {
+	path := strings.TrimSpace(expression)
+
+	switch {
+	case path == "" || path == ".":
+		return "$"
+	case strings.HasPrefix(path, "$"):
+		return path
+	case strings.HasPrefix(path, "@"):
+		return "$" + path[1:]
+	case strings.HasPrefix(path, "."):
+		return "$" + strings.ReplaceAll(path, "[]", "[*]")
+	}
+
+	return "$." + path
+}
+
+// getJSONValueText returns the text of a scalar JSON value
+func getJSONValueText(value interface{}) (string, error) {
+	switch typed := value.(type) {
+	case string:
+		return typed, nil
+	case json.Number:
+		return typed.String(), nil
+	case bool:
+		return strconv.FormatBool(typed), nil
+	case nil:
+		return "", newScrapeError(failureReasonXPath, "the JSONPath expression returned null instead of a value")
+	}
+
+	return "", newScrapeError(failureReasonXPath, "the JSONPath expression returned an object or array instead of a value")
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+)
+
+const testJSONDocument = `{
+	"status": "ok",
+	"version": "2.4.1",
+	"services": [
+		{"name": "api", "latency": 12.5, "up": true, "tags": ["public"]},
+		{"name": "db", "latency": 3, "up": false, "tags": []},
+		{"name": "cache", "latency": 0.8, "up": true}
+	],
+	"regions": {"us": {"load": 0.7}},
+	"odd key": 1
+}`
+
+func getTestJSONDocument(tb testing.TB) interface{} {
+	tb.Helper()
+
+	doc, err := parseJSONDocument([]byte(testJSONDocument))
+	ok(tb, err)
+
+	return doc
+}
+
+func TestQueryJSON(t *testing.T) {
+	doc := getTestJSONDocument(t)
+
+	tests := []struct {
+		expression string
+		expected   []interface{}
+	}{
+		{"$.status", []interface{}{"ok"}},
+		{"status", []interface{}{"ok"}},
+		{"$.services[0].name", []interface{}{"api"}},
+		{"$.services[*].name", []interface{}{"api", "db", "cache"}},
+		{"$[\"services\"][1][\"name\"]", []interface{}{"db"}},
+		{"$[\"odd key\"]", []interface{}{json.Number("1")}},
+		{"$.services[0,2].name", []interface{}{"api", "cache"}},
+		{"$.services[1:].name", []interface{}{"db", "cache"}},
+		{"$.services[:1].name", []interface{}{"api"}},
+		{"$.services[0].tags", []interface{}{"public"}},
+		{"$.regions.*.load", []interface{}{json.Number("0.7")}},
+		{"$..load", []interface{}{json.Number("0.7")}},
+		{"$.services[?(@.up == true)].name", []interface{}{"api", "cache"}},
+		{"$.services[?(@.latency > 1)].name", []interface{}{"api", "db"}},
+		{"$.services[?(@.name != \"db\")].name", []interface{}{"api", "cache"}},
+		{"$.services[?(@.up == true && @.latency < 1)].name", []interface{}{"cache"}},
+		{"$.services[0:3:2].name", []interface{}{"api", "cache"}},
+		{"@.version", []interface{}{"2.4.1"}},
+		{".services[].name", []interface{}{"api", "db", "cache"}},
+		{".services[1].latency", []interface{}{json.Number("3")}},
+		{"$.missing", nil},
+		{"$.status.missing", nil},
+		{"$.services[10]", nil},
+	}
+
+	for _, test := range tests {
+		results, err := queryJSON(doc, test.expression)
+		ok(t, err)
+		equals(t, test.expected, results)
+	}
+}
+
+func TestQueryJSON_root(t *testing.T) {
+	for _, expression := range []string{"$", "@", "."} {
+		results, err := queryJSON("foo", expression)
+		ok(t, err)
+		equals(t, []interface{}{"foo"}, results)
+	}
+}
+
+func TestQueryJSON_invalidExpression(t *testing.T) {
+	doc := getTestJSONDocument(t)
+
+	for _, expression := range []string{"$.services[0", "$..", "$.services[?(@.name == 'db')]"} {
+		_, err := queryJSON(doc, expression)
+		assert(t, err != nil, "expected JSONPath expression `%s` to fail", expression)
+		equals(t, failureReasonXPath, getFailureReason(err))
+	}
+}
+
+func getTestJSONScrapeConfig(metrics ...types.MetricConfig) types.ScrapeConfig {
+	server := getTestServer(testJSONDocument)
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Format = "json"
+	config.Metrics = metrics
+
+	return config
+}
+
+func TestScrape_json(t *testing.T) {
+	config := getTestJSONScrapeConfig(
+		types.MetricConfig{Name: "latency", Selector: "$.services[0].latency"},
+		types.MetricConfig{Name: "up", Selector: "$.services[1].up"},
+		types.MetricConfig{Name: "services", Selector: "$.services[*]", Mode: "count"},
+		types.MetricConfig{Name: "down", Selector: "$.services[?(@.up == false)]", Mode: "presence"},
+		types.MetricConfig{Name: "ok", Selector: ".status", ValueMappings: []types.ValueMappingConfig{{Match: "ok", Value: 1}}},
+	)
+
+	output, stats, err := scrape(context.Background(), config)
+	ok(t, err)
+
+	expected := []float64{12.5, 0, 3, 1, 1}
+	equals(t, len(expected), len(output))
+
+	for i, value := range expected {
+		equals(t, value, output[i].value)
+	}
+
+	equals(t, 3, stats.matchedNodes["services"])
+}
+
+func TestScrape_jsonLabelSelectors(t *testing.T) {
+	config := getTestJSONScrapeConfig(types.MetricConfig{
+		Name:           "service_latency",
+		Selector:       "$.services[*]",
+		ValueSelector:  "@.latency",
+		Labels:         map[string]string{"env": "prod"},
+		LabelSelectors: map[string]string{"service": "@.name", "up": ".up"},
+	})
+
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+	equals(t, 3, len(output))
+
+	equals(t, 0.8, output[2].value)
+	equals(t, map[string]string{"env": "prod", "service": "cache", "up": "true"}, output[2].labels)
+}
+
+func TestScrape_jsonInfo(t *testing.T) {
+	config := getTestJSONScrapeConfig(types.MetricConfig{
+		Name:           "app_info",
+		Type:           "info",
+		Selector:       "$",
+		LabelSelectors: map[string]string{"version": "$.version"},
+	})
+
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+	equals(t, 1, len(output))
+	equals(t, 1.0, output[0].value)
+	equals(t, "2.4.1", output[0].labels["version"])
+}
+
+func TestScrape_jsonNumbersIgnoreLocale(t *testing.T) {
+	config := getTestJSONScrapeConfig(types.MetricConfig{Name: "latency", Selector: "$.services[0].latency"})
+	config.Locale = "pt-BR"
+
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+	equals(t, 12.5, output[0].value)
+}
+
+func TestScrape_jsonErrors(t *testing.T) {
+	tests := []struct {
+		selector      string
+		expectedError string
+	}{
+		{"$.missing", "no elements returned by the JSONPath expression"},
+		{"$.services", "returned an object or array"},
+		{"$.services[0].name", "error parsing value api"},
+	}
+
+	for _, test := range tests {
+		config := getTestJSONScrapeConfig(types.MetricConfig{Name: "foo", Selector: test.selector})
+
+		_, _, err := scrape(context.Background(), config)
+		errorContains(t, err, test.expectedError)
+	}
+}
+
+func TestScrape_jsonInvalidBody(t *testing.T) {
+	server := getTestServer("<html></html>")
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Format = "json"
+
+	_, _, err := scrape(context.Background(), config)
+	errorContains(t, err, "error parsing the response body as JSON")
+	equals(t, failureReasonParse, getFailureReason(err))
+}
+
+func TestParseConfig_format(t *testing.T) {
+	config := []byte(`
+scrape_config:
+  address: "http://foo.dev"
+  format: yaml
+`)
+
+	_, err := parseConfig(config)
+	errorContains(t, err, "unknown format \"yaml\"")
+
+	config = []byte(`
+scrape_config:
+  address: "http://foo.dev"
+  format: json
+  metrics:
+    - name: foo
+      expression: "a + b"
+`)
+
+	_, err = parseConfig(config)
+	errorContains(t, err, "not supported with the json format")
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// numberParser parses a scraped value into a number
+type numberParser func(value string) (float64, error)
+
+type numberLocale struct {
+	decimalSeparator   string
+	groupingSeparators []string
+}
+
+var (
+	// the narrow no-break space (U+202F) and no-break space (U+00A0) are used for grouping by CLDR in many locales,
+	// but pages often show a plain space instead, so all of them are accepted
+	spaceGrouping = []string{" ", "\u00a0", "\u202f"}
+
+	pointDecimalCommaGrouping = numberLocale{decimalSeparator: ".", groupingSeparators: []string{","}}
+	commaDecimalPointGrouping = numberLocale{decimalSeparator: ",", groupingSeparators: []string{"."}}
+	commaDecimalSpaceGrouping = numberLocale{decimalSeparator: ",", groupingSeparators: spaceGrouping}
+	pointDecimalQuoteGrouping = numberLocale{decimalSeparator: ".", groupingSeparators: []string{"'", "\u2019"}}
+)
+
+// number formats by locale, and by language for locales not listed. Indian lakh grouping (12,34,567.89)
+// needs no special handling, since grouping separators are removed regardless of their position
+var numberLocales = map[string]numberLocale{
+	"en":    pointDecimalCommaGrouping,
+	"en-IN": pointDecimalCommaGrouping,
+	"hi":    pointDecimalCommaGrouping,
+	"ja":    pointDecimalCommaGrouping,
+	"ko":    pointDecimalCommaGrouping,
+	"zh":    pointDecimalCommaGrouping,
+	"th":    pointDecimalCommaGrouping,
+	"he":    pointDecimalCommaGrouping,
+	"pt":    commaDecimalPointGrouping,
+	"pt-BR": commaDecimalPointGrouping,
+	"pt-PT": commaDecimalSpaceGrouping,
+	"de":    commaDecimalPointGrouping,
+	"de-CH": pointDecimalQuoteGrouping,
+	"de-LI": pointDecimalQuoteGrouping,
+	"it-CH": pointDecimalQuoteGrouping,
+	"es":    commaDecimalPointGrouping,
+	"es-MX": pointDecimalCommaGrouping,
+	"it":    commaDecimalPointGrouping,
+	"nl":    commaDecimalPointGrouping,
+	"id":    commaDecimalPointGrouping,
+	"tr":    commaDecimalPointGrouping,
+	"da":    commaDecimalPointGrouping,
+	"el":    commaDecimalPointGrouping,
+	"ro":    commaDecimalPointGrouping,
+	"fr":    commaDecimalSpaceGrouping,
+	"fr-CH": commaDecimalSpaceGrouping,
+	"ru":    commaDecimalSpaceGrouping,
+	"uk":    commaDecimalSpaceGrouping,
+	"pl":    commaDecimalSpaceGrouping,
+	"cs":    commaDecimalSpaceGrouping,
+	"sk":    commaDecimalSpaceGrouping,
+	"hu":    commaDecimalSpaceGrouping,
+	"sv":    commaDecimalSpaceGrouping,
+	"nb":    commaDecimalSpaceGrouping,
+	"fi":    commaDecimalSpaceGrouping,
+}
+
+func getNumberLocale(locale string) (numberLocale, bool) {
+	if format, found := numberLocales[locale]; found {
+		return format, true
+	}
+
+	language := strings.SplitN(strings.ReplaceAll(locale, "_", "-"), "-", 2)[0]
+	format, found := numberLocales[language]
+
+	return format, found
+}
+
+// getNumberParser returns a parser for numbers in the format of `locale`, or with the separators if no locale is set
+func getNumberParser(locale string, thousandsSeparator string, decimalSeparator string) numberParser {
+	if locale == "" {
+		return func(value string) (float64, error) {
+			return normalizeNumericValue(value, thousandsSeparator, decimalSeparator)
+		}
+	}
+
+	return func(value string) (float64, error) {
+		return normalizeLocaleNumericValue(value, locale)
+	}
+}
+
+// normalizeLocaleNumericValue parses a number in the format of `locale`, accepting signs, the Unicode minus sign
+// and accounting-style negatives in parentheses, e.g. "(1,234)"
+func normalizeLocaleNumericValue(value string, locale string) (float64, error) {
+	format, found := getNumberLocale(locale)
+	if !found {
+		return 0, newScrapeError(failureReasonNumberFormat, "unknown locale \"%s\"", locale)
+	}
+
+	number := strings.TrimFunc(value, unicode.IsSpace)
+	number = strings.ReplaceAll(number, "\u2212", "-")
+
+	negative := false
+	if strings.HasPrefix(number, "(") && strings.HasSuffix(number, ")") {
+		negative = true
+		number = strings.TrimFunc(number[1:len(number)-1], unicode.IsSpace)
+	}
+
+	for _, separator := range format.groupingSeparators {
+		number = strings.ReplaceAll(number, separator, "")
+	}
+
+	number = strings.ReplaceAll(number, format.decimalSeparator, ".")
+
+	floatValue, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, newScrapeError(failureReasonNumberFormat, "error parsing value %s to a float with locale %s. error: %s", value, locale, err)
+	}
+
+	if negative {
+		floatValue = -floatValue
+	}
+
+	return floatValue, nil
+}
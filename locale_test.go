@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+)
+
+func TestNormalizeLocaleNumericValue(t *testing.T) {
+	tests := []struct {
+		value    string
+		locale   string
+		expected float64
+	}{
+		{"1.234.567,89", "pt-BR", 1234567.89},
+		{"1 234 567,89", "fr-FR", 1234567.89},
+		{"1 234,5", "fr", 1234.5},
+		{"1 234,5", "fr-FR", 1234.5},
+		{"1'234'567.50", "de-CH", 1234567.5},
+		{"1’234.50", "de-CH", 1234.5},
+		{"1.234,50", "de-DE", 1234.5},
+		{"12,34,567.89", "en-IN", 1234567.89},
+		{"1,234.5", "en_US", 1234.5},
+		{"-1.234,5", "pt-BR", -1234.5},
+		{"+42", "en", 42},
+		{"−1 234,5", "fr-FR", -1234.5},
+		{"(1,234.56)", "en-US", -1234.56},
+		{" ( 12,5 ) ", "pt-BR", -12.5},
+	}
+
+	for _, test := range tests {
+		value, err := normalizeLocaleNumericValue(test.value, test.locale)
+		ok(t, err)
+		assertFloatEquals(t, test.expected, value)
+	}
+}
+
+func TestNormalizeLocaleNumericValue_errors(t *testing.T) {
+	_, err := normalizeLocaleNumericValue("1,5", "xx-YY")
+	errorContains(t, err, "unknown locale \"xx-YY\"")
+
+	_, err = normalizeLocaleNumericValue("1,2,3", "pt-BR")
+	errorContains(t, err, "error parsing value 1,2,3")
+	equals(t, failureReasonNumberFormat, getFailureReason(err))
+}
+
+func TestScrape_locale(t *testing.T) {
+	server := getTestServer("<div id=\"foobar\">1 234,5</div>")
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Locale = "fr-FR"
+	config.Metrics = []types.MetricConfig{{Name: "foo"}}
+
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+	equals(t, 1234.5, output[0].value)
+}
+
+func TestParseConfig_unknownLocale(t *testing.T) {
+	config := []byte(`
+scrape_config:
+  address: "http://foo.dev"
+  locale: xx-YY
+  metrics:
+    - name: foo
+`)
+
+	_, err := parseConfig(config)
+	errorContains(t, err, "unknown locale \"xx-YY\" in metric \"foo\"")
+}
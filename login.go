@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// loginSession holds the cookies of a target that requires logging in, reused between scrapes
+type loginSession struct {
+	mutex sync.Mutex
+	jar   http.CookieJar
+	// address is the login address, resolved against the address of the target
+	address  string
+	loggedIn bool
+}
+
+var (
+	loginSessionsMutex sync.Mutex
+	loginSessions      = make(map[string]*loginSession)
+)
+
+// getLoginSession returns the session of the login address of the scrape config. a relative login address is resolved
+// against the scrape address, so each target of a module has its own session
+func getLoginSession(config types.ScrapeConfig) (*loginSession, error) {
+	address, err := getLoginAddress(config)
+	if err != nil {
+		return nil, newScrapeError(failureReasonAuth, "invalid login address %s. error: %s", redactURL(config.Login.Address), err)
+	}
+
+	loginSessionsMutex.Lock()
+	defer loginSessionsMutex.Unlock()
+
+	key := config.Name + "|" + address
+
+	session, found := loginSessions[key]
+	if !found {
+		// cookiejar.New never returns an error without options
+		jar, _ := cookiejar.New(nil)
+		session = &loginSession{jar: jar, address: address}
+		loginSessions[key] = session
+	}
+
+	return session, nil
+}
+
+func getLoginAddress(config types.ScrapeConfig) (string, error) {
+	scrapeURL, err := url.Parse(config.Address)
+	if err != nil {
+		return "", err
+	}
+
+	loginURL, err := url.Parse(config.Login.Address)
+	if err != nil {
+		return "", err
+	}
+
+	return scrapeURL.ResolveReference(loginURL).String(), nil
+}
+
+// login submits the login form of the scrape config, unless already logged in and `force` is false
+func (s *loginSession) login(ctx context.Context, client *http.Client, config types.ScrapeConfig, force bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.loggedIn && !force {
+		return nil
+	}
+
+	s.loggedIn = false
+	loginConfig := config.Login
+
+	form, err := getLoginForm(*loginConfig)
+	if err != nil {
+		return newScrapeError(failureReasonAuth, "unable to read the login form fields. error: %s", err)
+	}
+
+	method := loginConfig.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.address, strings.NewReader(form.Encode()))
+	if err != nil {
+		return newScrapeError(failureReasonAuth, "unable to create login request. error: %s", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", fmt.Sprintf("prometheus-html-exporter/%s", BuildVersion))
+
+	log.Infof("logging in to %s", redactURL(s.address))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return newScrapeError(failureReasonAuth, "unable to request login URL %s. error: %s", redactURL(s.address), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return newScrapeError(failureReasonAuth, "login error: %s", resp.Status)
+	}
+
+	if loginConfig.SuccessSelector != "" {
+		doc, err := parseDocument(resp.Body)
+		if err != nil {
+			return err
+		}
+
+		_, err = queryNodes(doc, loginConfig.SuccessSelector, config.SelectorType)
+		if err != nil {
+			return newScrapeError(failureReasonAuth, "login failed, the success check did not match. %s", err)
+		}
+	}
+
+	s.loggedIn = true
+
+	return nil
+}
+
+func getLoginForm(config types.LoginConfig) (url.Values, error) {
+	form := url.Values{}
+
+	for field, value := range config.Form {
+		form.Set(field, value)
+	}
+
+	for field, file := range config.FormFiles {
+		value, err := readSecret("", file, "")
+		if err != nil {
+			return nil, fmt.Errorf("error reading field %s. %s", field, err)
+		}
+
+		form.Set(field, value)
+	}
+
+	for field, env := range config.FormEnv {
+		value, err := readSecret("", "", env)
+		if err != nil {
+			return nil, fmt.Errorf("error reading field %s. %s", field, err)
+		}
+
+		form.Set(field, value)
+	}
+
+	return form, nil
+}
+
+// isLoginRedirect returns whether the response was redirected to the login form at `loginAddress`
+func isLoginRedirect(resp *http.Response, loginAddress string) bool {
+	loginURL, err := url.Parse(loginAddress)
+	if err != nil {
+		return false
+	}
+
+	// a request that wasn't redirected has the original URL, which may be the login page itself
+	if resp.Request == nil || resp.Request.Response == nil {
+		return false
+	}
+
+	return resp.Request.URL.Host == loginURL.Host && resp.Request.URL.Path == loginURL.Path
+}
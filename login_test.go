@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+)
+
+type testLoginServer struct {
+	*httptest.Server
+	logins  int
+	session string
+}
+
+// getTestLoginServer returns a server whose /status page requires logging in with the foo/bar credentials at /login
+func getTestLoginServer() *testLoginServer {
+	server := &testLoginServer{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			fmt.Fprintln(w, "<form id=\"login\"></form>")
+			return
+		}
+
+		if r.PostFormValue("username") != "foo" || r.PostFormValue("password") != "bar" {
+			fmt.Fprintln(w, "<div id=\"error\">Invalid credentials</div>")
+			return
+		}
+
+		server.logins++
+		server.session = fmt.Sprintf("session-%d", server.logins)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: server.session, Path: "/"})
+		fmt.Fprintln(w, "<div id=\"welcome\">Welcome</div>")
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		if err != nil || cookie.Value != server.session {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+
+		fmt.Fprintln(w, "<div id=\"foobar\">42</div>")
+	})
+
+	server.Server = httptest.NewServer(mux)
+
+	return server
+}
+
+func getTestLoginScrapeConfig(t *testing.T, server *testLoginServer) types.ScrapeConfig {
+	config := testScrapeConfig
+	config.Name = t.Name()
+	config.Address = server.URL + "/status"
+	config.Login = &types.LoginConfig{
+		Address:         server.URL + "/login",
+		Form:            map[string]string{"username": "foo"},
+		FormEnv:         map[string]string{"password": "HTML_EXPORTER_TEST_PASSWORD"},
+		SuccessSelector: "//div[@id='welcome']",
+	}
+
+	return config
+}
+
+func TestScrape_login(t *testing.T) {
+	t.Setenv("HTML_EXPORTER_TEST_PASSWORD", "bar")
+	server := getTestLoginServer()
+	config := getTestLoginScrapeConfig(t, server)
+
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+	equals(t, 42.0, output[0].value)
+
+	// the session cookie should be reused by the following scrapes
+	_, _, err = scrape(context.Background(), config)
+	ok(t, err)
+	equals(t, 1, server.logins)
+}
+
+func TestScrape_loginExpiredSession(t *testing.T) {
+	t.Setenv("HTML_EXPORTER_TEST_PASSWORD", "bar")
+	server := getTestLoginServer()
+	config := getTestLoginScrapeConfig(t, server)
+
+	_, _, err := scrape(context.Background(), config)
+	ok(t, err)
+
+	server.session = "expired"
+
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+	equals(t, 42.0, output[0].value)
+	equals(t, 2, server.logins)
+}
+
+func TestScrape_loginModuleTargets(t *testing.T) {
+	t.Setenv("HTML_EXPORTER_TEST_PASSWORD", "bar")
+	server := getTestLoginServer()
+	otherServer := getTestLoginServer()
+
+	module := getTestLoginScrapeConfig(t, server)
+	module.Login.Address = "/login"
+	config := types.ExporterConfig{Modules: map[string]types.ScrapeConfig{"appliance": module}}
+
+	// each target logs in to its own host, with its own session
+	for _, target := range []*testLoginServer{server, otherServer, server, otherServer} {
+		scrapeConfig, err := getModuleScrapeConfig(config, "appliance", target.URL+"/status")
+		ok(t, err)
+
+		output, _, err := scrape(context.Background(), scrapeConfig)
+		ok(t, err)
+		equals(t, 42.0, output[0].value)
+	}
+
+	equals(t, 1, server.logins)
+	equals(t, 1, otherServer.logins)
+}
+
+func TestScrape_loginInvalidCredentials(t *testing.T) {
+	t.Setenv("HTML_EXPORTER_TEST_PASSWORD", "wrong")
+	server := getTestLoginServer()
+	config := getTestLoginScrapeConfig(t, server)
+
+	_, _, err := scrape(context.Background(), config)
+	errorContains(t, err, "login failed")
+	equals(t, failureReasonAuth, getFailureReason(err))
+}
+
+func TestIsLoginRedirect(t *testing.T) {
+	loginAddress := "http://foo.dev/login"
+
+	redirectedReq, err := http.NewRequest("GET", "http://foo.dev/login?next=/status", nil)
+	ok(t, err)
+	redirectedReq.Response = &http.Response{StatusCode: http.StatusFound}
+
+	assert(t, isLoginRedirect(&http.Response{Request: redirectedReq}, loginAddress), "expected a redirect to the login page to be detected")
+
+	req, err := http.NewRequest("GET", "http://foo.dev/login", nil)
+	ok(t, err)
+
+	assert(t, !isLoginRedirect(&http.Response{Request: req}, loginAddress), "expected a request to the login page without redirects not to be detected")
+}
@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"net/http"
 	"os"
-	"time"
+	"os/signal"
+	"sync"
+	"syscall"
 
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
 	"github.com/akamensky/argparse"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
@@ -26,23 +29,30 @@ func main() {
 	}
 
 	config := getConfig(configFile)
+	setConfigReloadStatus(true)
+
 	metricRegistry, err := getExporterMetricsRegistry()
 
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	var configMutex sync.RWMutex
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go reloadConfigOnSignal(signals, configFile.Name(), &config, &configMutex)
+
 	http.HandleFunc("/probe", func(w http.ResponseWriter, r *http.Request) {
-		probeHandler(w, r, config)
+		configMutex.RLock()
+		currentConfig := config
+		configMutex.RUnlock()
+
+		probeHandler(w, r, currentConfig)
 	})
 
 	http.Handle("/metrics", promhttp.HandlerFor(metricRegistry, promhttp.HandlerOpts{}))
 
-	server := &http.Server{
-		ReadTimeout:  1 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		Addr:         fmt.Sprintf(":%d", config.GlobalConfig.Port),
-	}
+	server := newHTTPServer(config.GlobalConfig.Port, http.DefaultServeMux)
 
 	log.Infof("Server starting and listening on port %d", config.GlobalConfig.Port)
 
@@ -50,3 +60,30 @@ func main() {
 		log.Fatalf("server error: %s", err.Error())
 	}
 }
+
+// reloadConfigOnSignal replaces `config` with the contents of the configuration file whenever a signal,
+// i.e. a SIGHUP, is received, until `signals` is closed
+func reloadConfigOnSignal(signals <-chan os.Signal, path string, config *types.ExporterConfig, configMutex *sync.RWMutex) {
+	for range signals {
+		log.Infof("reloading configuration file %s", path)
+
+		newConfig, err := loadConfigFile(path)
+		if err != nil {
+			log.Errorf("error reloading configuration, keeping the previous one: %s", err)
+			setConfigReloadStatus(false)
+			continue
+		}
+
+		if newConfig.GlobalConfig.Port != config.GlobalConfig.Port {
+			log.Warnf("changing the port requires a restart, the server will keep listening on port %d", config.GlobalConfig.Port)
+		}
+
+		configMutex.Lock()
+		*config = newConfig
+		configMutex.Unlock()
+
+		resetTransports()
+
+		setConfigReloadStatus(true)
+	}
+}
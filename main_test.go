@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// sendReloadSignal runs reloadConfigOnSignal until it has handled a single SIGHUP
+func sendReloadSignal(path string, config *types.ExporterConfig) {
+	signals := make(chan os.Signal, 1)
+	signals <- syscall.SIGHUP
+	close(signals)
+
+	var configMutex sync.RWMutex
+	reloadConfigOnSignal(signals, path, config, &configMutex)
+}
+
+func TestReloadConfigOnSignal(t *testing.T) {
+	config := getDefaultConfig()
+	setConfigReloadStatus(false)
+
+	sendReloadSignal(path.Join(getTestDir(t), "sample-config.yaml"), &config)
+
+	equals(t, "https://en.wikipedia.org/wiki/Special:Statistics", config.ScrapeConfigs[0].Address)
+	equals(t, 1.0, testutil.ToFloat64(configLastReloadSuccessful))
+	assert(t, testutil.ToFloat64(configLastReloadSuccessTimestamp) > 0, "expected the reload timestamp to be set")
+}
+
+func TestReloadConfigOnSignal_invalidConfig(t *testing.T) {
+	config := getDefaultConfig()
+	config.ScrapeConfigs = []types.ScrapeConfig{{Address: "http://foo.dev"}}
+	setConfigReloadStatus(true)
+
+	sendReloadSignal(path.Join(getTestDir(t), "sample-config-invalid-syntax.notyaml"), &config)
+
+	equals(t, []types.ScrapeConfig{{Address: "http://foo.dev"}}, config.ScrapeConfigs)
+	equals(t, 0.0, testutil.ToFloat64(configLastReloadSuccessful))
+}
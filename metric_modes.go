@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+)
+
+// modes of a metric, defining how its value is taken from the nodes matched by the selector
+const (
+	// metricModeValue parses the matched node as a number. it's the default mode
+	metricModeValue = "value"
+	// metricModePresence is 1 if the selector matched any node, or 0 otherwise
+	metricModePresence = "presence"
+	// metricModeCount is the number of nodes matched by the selector
+	metricModeCount = "count"
+	// metricModeLength is the number of characters of the text of the matched node
+	metricModeLength = "length"
+)
+
+var metricModes = map[string]bool{
+	"":                 true,
+	metricModeValue:    true,
+	metricModePresence: true,
+	metricModeCount:    true,
+	metricModeLength:   true,
+}
+
+// getNodeCountValues returns the sample of a metric in the presence or count mode with `count` matched nodes
+func getNodeCountValues(count int, metricConfig types.MetricConfig) []metricValue {
+	value := float64(count)
+	if metricConfig.Mode == metricModePresence && value > 1 {
+		value = 1
+	}
+
+	return []metricValue{{metricConfig: metricConfig, value: value, labels: metricConfig.Labels}}
+}
+
+// getLengthValue returns the number of characters of a scraped value after its transforms are applied,
+// ignoring surrounding whitespace
+func getLengthValue(value string, metricConfig types.MetricConfig) (float64, error) {
+	value, err := applyTransforms(value, metricConfig.Transforms)
+	if err != nil {
+		return 0, err
+	}
+
+	return float64(utf8.RuneCountInString(strings.TrimSpace(value))), nil
+}
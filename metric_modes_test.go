@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestScrape_metricModes(t *testing.T) {
+	server := getTestServer(`<div class="banner">Scheduled maintenance</div>
+<ul><li class="incident">API</li><li class="incident">Database</li></ul>`)
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Metrics = []types.MetricConfig{
+		{Name: "maintenance", Mode: "presence", Selector: "//div[@class='banner']"},
+		{Name: "outage", Mode: "presence", Selector: "//div[@class='outage']"},
+		{Name: "incidents", Mode: "count", Selector: "//li[@class='incident']"},
+		{Name: "no_incidents", Mode: "count", Selector: "//li[@class='resolved']"},
+		{Name: "banner_length", Mode: "length", Selector: "//div[@class='banner']"},
+	}
+
+	output, stats, err := scrape(context.Background(), config)
+	ok(t, err)
+
+	expected := []float64{1, 0, 2, 0, 21}
+	equals(t, len(expected), len(output))
+
+	for i, value := range expected {
+		equals(t, value, output[i].value)
+	}
+
+	equals(t, 2, stats.matchedNodes["incidents"])
+	equals(t, 0, stats.matchedNodes["outage"])
+}
+
+func TestScrape_lengthModeLabelSelectors(t *testing.T) {
+	server := getTestServer(`<p><span>a</span><em>ab</em></p><p><span>b</span><em> héllo </em></p>`)
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Metrics = []types.MetricConfig{{
+		Name:           "item_length",
+		Mode:           "length",
+		Selector:       "//em",
+		LabelSelectors: map[string]string{"id": "../span/text()"},
+	}}
+
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+	equals(t, 2, len(output))
+	equals(t, 2.0, output[0].value)
+	equals(t, 5.0, output[1].value)
+	equals(t, "b", output[1].labels["id"])
+}
+
+func TestScrape_countModeInvalidXPath(t *testing.T) {
+	server := getTestServer("<div></div>")
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Metrics = []types.MetricConfig{{Name: "foo", Mode: "count", Selector: "/`$/"}}
+
+	_, _, err := scrape(context.Background(), config)
+	equals(t, failureReasonXPath, getFailureReason(err))
+}
+
+func TestParseConfig_unknownMode(t *testing.T) {
+	config := []byte(`
+scrape_config:
+  address: "http://foo.dev"
+  metrics:
+    - name: foo
+      mode: exists
+`)
+
+	_, err := parseConfig(config)
+	errorContains(t, err, "unknown mode \"exists\" in metric \"foo\"")
+}
+
+func TestParseConfig_countModeLabelSelectors(t *testing.T) {
+	tests := []string{
+		"mode: count",
+		"mode: presence",
+		"expression: a",
+		"table: {value_columns: [Requests]}",
+		"extract: evaluate",
+	}
+
+	for _, test := range tests {
+		config := []byte(`
+scrape_config:
+  address: "http://foo.dev"
+  metrics:
+    - name: foo
+      selector: "//li"
+      label_selectors:
+        item: "./text()"
+      inputs:
+        a:
+          selector: "//li"
+      ` + test + `
+`)
+
+		_, err := parseConfig(config)
+		errorContains(t, err, "metric \"foo\" can't have label selectors")
+	}
+}
+
+func TestCollect_countMode(t *testing.T) {
+	server := getTestServer(`<ul><li class="incident">API</li><li class="incident">Database</li></ul>`)
+
+	config, err := parseConfig([]byte(`
+scrape_config:
+  name: foo
+  address: "` + server.URL + `"
+  metrics:
+    - name: incidents
+      type: gauge
+      help: "Open incidents"
+      mode: count
+      selector: "//li[@class='incident']"
+      labels:
+        page: status
+`))
+	ok(t, err)
+
+	collector := collector{ctx: context.Background(), scrapeConfigs: config.ScrapeConfigs, globalConfig: config.GlobalConfig}
+
+	expected := `
+# HELP htmlexporter_incidents Open incidents
+# TYPE htmlexporter_incidents gauge
+htmlexporter_incidents{page="status"} 2
+`
+
+	err = testutil.CollectAndCompare(collector, strings.NewReader(expected), "htmlexporter_incidents")
+	ok(t, err)
+}
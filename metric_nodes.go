@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// metricNode is a node of a scraped document, e.g. an HTML element or a JSON value. it hides the format of the
+// document, so the modes, aggregates and label selectors of the metrics work the same way for every format
+type metricNode interface {
+	// query returns the nodes matched by `selector` relative to this node, which may be none
+	query(selector string) ([]metricNode, error)
+	// text returns the text of the node, used as the value of labels
+	text() (string, error)
+	// metricValues returns the samples of the metric for the value of the node
+	metricValues(metricConfig types.MetricConfig, labels map[string]string) ([]metricValue, error)
+	// selectorDescription names the kind of selectors used on the node in error messages, e.g. "XPath expression"
+	selectorDescription() string
+}
+
+// scrapeNodeMetric returns the samples of a metric, selected by its selector from the document `doc`
+func scrapeNodeMetric(doc metricNode, metricConfig types.MetricConfig, stats *scrapeStats) ([]metricValue, error) {
+	stats.matchedNodes[metricConfig.Name] = 0
+
+	nodes, err := doc.query(metricConfig.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.matchedNodes[metricConfig.Name] = len(nodes)
+
+	// unlike the other modes, matching no nodes is not an error
+	if metricConfig.Mode == metricModePresence || metricConfig.Mode == metricModeCount {
+		return getNodeCountValues(len(nodes), metricConfig), nil
+	}
+
+	// the sum and count of no values are 0, so these aggregates don't need any node either
+	if len(nodes) < 1 && metricConfig.Aggregate != aggregateSum && metricConfig.Aggregate != aggregateCount {
+		return nil, newScrapeError(failureReasonXPath, "no elements returned by the %s `%s`", doc.selectorDescription(), metricConfig.Selector)
+	}
+
+	if metricConfig.Aggregate != "" {
+		return aggregateValues(len(nodes), func(i int) (float64, error) {
+			values, err := getNodeMetricValues(nodes[i], metricConfig, nil)
+			if err != nil {
+				return 0, err
+			}
+
+			return values[0].value, nil
+		}, metricConfig)
+	}
+
+	// every node matched by the selector is exported as its own sample only when the metric has label selectors
+	if len(metricConfig.LabelSelectors) == 0 {
+		if len(nodes) > 1 {
+			log.Warnf("more than one element was returned by the %s. only the value of the first element will be exported", doc.selectorDescription())
+		}
+
+		nodes = nodes[:1]
+	}
+
+	var metricValues []metricValue
+
+	for _, node := range nodes {
+		labels, err := parseLabelSelectors(node, metricConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		values, err := getNodeMetricValues(node, metricConfig, labels)
+		if err != nil {
+			return nil, err
+		}
+
+		metricValues = append(metricValues, values...)
+	}
+
+	return metricValues, nil
+}
+
+// getNodeMetricValues returns the samples of the metric for a node matched by its selector, taking the value
+// from the node selected by the value selector if set
+func getNodeMetricValues(node metricNode, metricConfig types.MetricConfig, labels map[string]string) ([]metricValue, error) {
+	// info metrics are always 1, so the node doesn't need to hold a value
+	if metricConfig.Type == metricTypeInfo {
+		return []metricValue{{metricConfig: metricConfig, value: 1, labels: labels}}, nil
+	}
+
+	if metricConfig.ValueSelector != "" {
+		var err error
+
+		node, err = queryFirstNode(node, metricConfig.ValueSelector)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return node.metricValues(metricConfig, labels)
+}
+
+// parseLabelSelectors returns the static labels of the metric, along with the labels extracted
+// by evaluating the metric label selectors relative to `node`
+func parseLabelSelectors(node metricNode, metricConfig types.MetricConfig) (map[string]string, error) {
+	labels := make(map[string]string, len(metricConfig.Labels)+len(metricConfig.LabelSelectors))
+
+	for name, value := range metricConfig.Labels {
+		labels[name] = value
+	}
+
+	for name, selector := range metricConfig.LabelSelectors {
+		labelNode, err := queryFirstNode(node, selector)
+		if err != nil {
+			return nil, fmt.Errorf("error extracting label \"%s\". %w", name, err)
+		}
+
+		value, err := labelNode.text()
+		if err != nil {
+			return nil, fmt.Errorf("error extracting label \"%s\". %w", name, err)
+		}
+
+		labels[name], err = sanitizeLabelValue(value, metricConfig)
+		if err != nil {
+			return nil, fmt.Errorf("error extracting label \"%s\". %w", name, err)
+		}
+	}
+
+	return labels, nil
+}
+
+// queryFirstNode returns the first node matched by `selector` relative to `node`, failing if it matches nothing
+func queryFirstNode(node metricNode, selector string) (metricNode, error) {
+	nodes, err := node.query(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nodes) < 1 {
+		return nil, newScrapeError(failureReasonXPath, "no elements returned by the %s `%s`", node.selectorDescription(), selector)
+	}
+
+	return nodes[0], nil
+}
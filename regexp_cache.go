@@ -0,0 +1,26 @@
+package main
+
+import (
+	"regexp"
+	"sync"
+)
+
+// compiledRegexps caches the regular expressions of the configuration, which are compiled when the configuration
+// is validated, so scrapes don't compile them again for every value
+var compiledRegexps sync.Map
+
+// compileRegexp returns the compiled regular expression `pattern`, compiling it only the first time it's used
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	if compiled, found := compiledRegexps.Load(pattern); found {
+		return compiled.(*regexp.Regexp), nil
+	}
+
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	compiledRegexps.Store(pattern, compiled)
+
+	return compiled, nil
+}
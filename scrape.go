@@ -1,93 +1,372 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
 	"github.com/antchfx/htmlquery"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
 )
 
-func scrape(config types.ScrapeConfig) (float64, error) {
-	log.Debugf("requesting URL '%s'", config.Address)
-	body, err := doRequest(config.Address)
+type metricValue struct {
+	metricConfig types.MetricConfig
+	value        float64
+	labels       map[string]string
+}
+
+// scrape returns the metric values of the target, along with statistics about the scrape even if it fails
+func scrape(ctx context.Context, config types.ScrapeConfig) ([]metricValue, *scrapeStats, error) {
+	stats := newScrapeStats()
+
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
+	}
+
+	if len(config.Steps) > 0 {
+		var err error
+
+		config, err = runSteps(ctx, config, stats)
+		if err != nil {
+			return nil, stats, err
+		}
+	}
+
+	log.Debugf("requesting URL '%s'", redactURL(config.Address))
+	body, err := doRequest(ctx, config, stats)
 	if err != nil {
-		return 0, err
+		return nil, stats, err
 	}
+	defer body.Close()
 
-	log.Debugf("scraping value from requested URL with XPath selector '%s'", config.Selector)
-	scrapedValue, err := parseSelector(body, config.Selector)
+	start := time.Now()
+	bodyBytes, err := io.ReadAll(body)
+	if err != nil {
+		return nil, stats, newScrapeError(failureReasonRequest, "error reading the response body. error: %s", err)
+	}
 
+	stats.addPhaseDuration(phaseDownload, time.Since(start))
+	stats.bodySize = len(bodyBytes)
+
+	switch config.Format {
+	case formatJSON:
+		metricValues, err := scrapeJSON(bodyBytes, config, stats)
+		return metricValues, stats, err
+	case formatXML:
+		metricValues, err := scrapeXML(bodyBytes, config, stats)
+		return metricValues, stats, err
+	}
+
+	start = time.Now()
+	doc, err := parseDocument(bytes.NewReader(bodyBytes))
 	if err != nil {
-		return 0, err
+		return nil, stats, err
+	}
+
+	stats.addPhaseDuration(phaseParse, time.Since(start))
+
+	var metricValues []metricValue
+	documents := map[string]*html.Node{config.Address: doc}
+
+	for _, metricConfig := range getMetricConfigs(config) {
+		var values []metricValue
+
+		if metricConfig.Expression != "" {
+			log.Debugf("evaluating expression '%s'", metricConfig.Expression)
+			values, err = scrapeExpression(ctx, config, documents, metricConfig, stats)
+		} else {
+			log.Debugf("scraping value from requested URL with XPath selector '%s'", metricConfig.Selector)
+			start = time.Now()
+			values, err = scrapeMetric(doc, metricConfig, stats)
+			stats.addPhaseDuration(phaseXPath, time.Since(start))
+		}
+
+		if err != nil {
+			return nil, stats, err
+		}
+
+		log.Debugf("scraped %d value(s) for metric '%s' from URL '%s'", len(values), metricConfig.Name, redactURL(config.Address))
+		metricValues = append(metricValues, values...)
+	}
+
+	return metricValues, stats, nil
+}
+
+func scrapeMetric(doc *html.Node, metricConfig types.MetricConfig, stats *scrapeStats) ([]metricValue, error) {
+	// evaluations and tables are only supported by HTML pages, so they don't go through the shared node logic
+	if metricConfig.Extract == extractEvaluate {
+		stats.matchedNodes[metricConfig.Name] = 0
+		return scrapeXPathEvaluation(doc, metricConfig, stats)
+	}
+
+	if metricConfig.Table != nil {
+		stats.matchedNodes[metricConfig.Name] = 0
+
+		nodes, err := queryNodes(doc, metricConfig.Selector, metricConfig.SelectorType)
+		if err != nil {
+			return nil, err
+		}
+
+		stats.matchedNodes[metricConfig.Name] = len(nodes)
+
+		return scrapeTable(nodes[0], metricConfig)
+	}
+
+	return scrapeNodeMetric(htmlNode{node: doc, selectorType: metricConfig.SelectorType}, metricConfig, stats)
+}
+
+// htmlNode is a node of an HTML page, queried with the selector type of the metric
+type htmlNode struct {
+	node         *html.Node
+	selectorType string
+}
+
+func (n htmlNode) query(selector string) ([]metricNode, error) {
+	node := n.node
+
+	// the values extracted by CSS pseudo-elements are text nodes, which have no descendants for CSS selectors
+	// to match, so these are queried from the element the value was extracted from
+	if n.selectorType == selectorTypeCSS && node.Type == html.TextNode && node.Parent != nil {
+		node = node.Parent
 	}
 
-	numberValue, err := normalizeNumericValue(scrapedValue, config.ThousandsSeparator, config.DecimalPointSeparator)
+	nodes, err := findNodes(node, selector, n.selectorType)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	results := make([]metricNode, len(nodes))
+	for i, node := range nodes {
+		results[i] = htmlNode{node: node, selectorType: n.selectorType}
 	}
 
-	log.Debugf("scraped value '%0.2f' from URL '%s'", numberValue, config.Address)
-	return numberValue, nil
+	return results, nil
 }
 
-func doRequest(url string) (io.ReadCloser, error) {
-	// @TODO: Allow passing headers, timeout and other request args
-	client := &http.Client{
-		// Timeout: 10000,
+func (n htmlNode) text() (string, error) {
+	return getNodeText(n.node), nil
+}
+
+func (n htmlNode) metricValues(metricConfig types.MetricConfig, labels map[string]string) ([]metricValue, error) {
+	value, err := getMetricNodeValue(n.node, metricConfig)
+	if err != nil {
+		return nil, err
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	return makeMetricValues(value, metricConfig, labels)
+}
+
+func (n htmlNode) selectorDescription() string {
+	return getSelectorDescription(n.selectorType)
+}
+
+func doRequest(ctx context.Context, config types.ScrapeConfig, stats *scrapeStats) (io.ReadCloser, error) {
+	client, err := newHTTPClient(config)
 	if err != nil {
-		return nil, fmt.Errorf("unable to create request. error: %s", err)
+		return nil, err
 	}
 
-	req.Header.Add("User-Agent", fmt.Sprintf("prometheus-html-exporter/%s", BuildVersion))
+	var session *loginSession
 
-	log.Infof("scraping page %s", url)
+	if config.Login != nil {
+		session, err = getLoginSession(config)
+		if err != nil {
+			return nil, err
+		}
 
-	resp, err := client.Do(req)
+		client.Jar = session.jar
+
+		err = session.login(ctx, client, config, false)
+		if err != nil {
+			return nil, err
+		}
+	}
 
+	resp, err := sendRequest(ctx, client, config, stats)
 	if err != nil {
-		return nil, fmt.Errorf("unable to request URL %s. error: %s", url, err)
+		return nil, err
+	}
+
+	// an expired session redirects back to the login form, so we log in again and retry once
+	if session != nil && isLoginRedirect(resp, session.address) {
+		resp.Body.Close()
+		log.Infof("session of target \"%s\" expired, logging in again", config.Name)
+
+		err = session.login(ctx, client, config, true)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err = sendRequest(ctx, client, config, stats)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	stats.statusCode = resp.StatusCode
+
+	if resp.TLS != nil {
+		stats.certExpiry = getEarliestCertExpiry(resp.TLS)
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode > 400 {
-		return nil, fmt.Errorf("request error: %s", resp.Status)
+		resp.Body.Close()
+		return nil, newScrapeError(failureReasonHTTPStatus, "request error: %s", resp.Status)
 	}
 
 	return resp.Body, nil
 }
 
-func parseSelector(body io.ReadCloser, selector string) (string, error) {
+func newHTTPClient(config types.ScrapeConfig) (*http.Client, error) {
+	transport, err := getTransport(config)
+	if err != nil {
+		return nil, newScrapeError(failureReasonTLS, "unable to set the TLS configuration. error: %s", err)
+	}
+
+	client := &http.Client{
+		Transport:     transport,
+		CheckRedirect: getCheckRedirectFunc(config),
+	}
+
+	return client, nil
+}
+
+func sendRequest(ctx context.Context, client *http.Client, config types.ScrapeConfig, stats *scrapeStats) (*http.Response, error) {
+	var body io.Reader
+	if config.Body != "" {
+		body = strings.NewReader(config.Body)
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, stats.clientTrace()), config.Method, config.Address, body)
+	if err != nil {
+		return nil, newScrapeError(failureReasonRequest, "unable to create request. error: %s", err)
+	}
+
+	req.Header.Add("User-Agent", fmt.Sprintf("prometheus-html-exporter/%s", BuildVersion))
+
+	for name, value := range config.Headers {
+		req.Header.Set(name, value)
+	}
+
+	err = applyAuth(req, config)
+	if err != nil {
+		return nil, newScrapeError(failureReasonAuth, "unable to set the request credentials. error: %s", err)
+	}
+
+	log.Infof("scraping page %s", redactURL(config.Address))
+
+	resp, err := client.Do(req)
+
+	if err != nil && isTLSError(err) {
+		return nil, newScrapeError(failureReasonTLS, "TLS error requesting URL %s. error: %s", redactURL(config.Address), err)
+	}
+
+	if err != nil {
+		return nil, newScrapeError(failureReasonRequest, "unable to request URL %s. error: %s", redactURL(config.Address), err)
+	}
+
+	return resp, nil
+}
+
+func getCheckRedirectFunc(config types.ScrapeConfig) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if config.FollowRedirects != nil && !*config.FollowRedirects {
+			return http.ErrUseLastResponse
+		}
+
+		maxRedirects := config.MaxRedirects
+		if maxRedirects == 0 {
+			maxRedirects = getDefaultConfig().ScrapeConfig.MaxRedirects
+		}
+
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+
+		return nil
+	}
+}
+
+func parseDocument(body io.Reader) (*html.Node, error) {
 	doc, err := htmlquery.Parse(body)
 
 	if err != nil {
-		return "", fmt.Errorf("error loading the response body into XPath nodes. error: %s", err)
+		return nil, newScrapeError(failureReasonParse, "error loading the response body into XPath nodes. error: %s", err)
 	}
 
-	nodes, err := htmlquery.QueryAll(doc, selector)
+	return doc, nil
+}
 
+// queryNodes returns the nodes matched by `selector`, an XPath expression or a CSS selector depending on
+// `selectorType`, failing if it matches nothing
+func queryNodes(doc *html.Node, selector string, selectorType string) ([]*html.Node, error) {
+	nodes, err := findNodes(doc, selector, selectorType)
 	if err != nil {
-		return "", fmt.Errorf("error querying the XPath expression `%s`. error: %s", selector, err)
+		return nil, err
 	}
 
 	if len(nodes) < 1 {
-		return "", fmt.Errorf("no elements returned by the XPath expression `%s`", selector)
+		return nil, newScrapeError(failureReasonXPath, "no elements returned by the %s `%s`", getSelectorDescription(selectorType), selector)
 	}
 
+	return nodes, nil
+}
+
+// findNodes returns the nodes matched by `selector`, which may be none
+func findNodes(doc *html.Node, selector string, selectorType string) ([]*html.Node, error) {
+	if selectorType == selectorTypeCSS {
+		return findCSSNodes(doc, selector)
+	}
+
+	nodes, err := htmlquery.QueryAll(doc, selector)
+
+	if err != nil {
+		return nil, newScrapeError(failureReasonXPath, "error querying the XPath expression `%s`. error: %s", selector, err)
+	}
+
+	return nodes, nil
+}
+
+func parseSelector(doc *html.Node, selector string, selectorType string) (string, error) {
+	nodes, err := queryNodes(doc, selector, selectorType)
+
+	if err != nil {
+		return "", err
+	}
+
+	return getNodeText(getFirstNode(nodes)), nil
+}
+
+// getNodeText returns the text of text nodes, and the text content of element and attribute nodes
+func getNodeText(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+
+	return htmlquery.InnerText(node)
+}
+
+func getFirstNodeValue(nodes []*html.Node) string {
+	return getNodeText(getFirstNode(nodes))
+}
+
+func getFirstNode(nodes []*html.Node) *html.Node {
 	// currently supporting only one attribute. this could change in the future if necessary
 	if len(nodes) > 1 {
 		log.Warn("more than one element was returned by the XPath expression. only the value of the first element will be exported")
 	}
 
-	value := nodes[0].Data
-
-	return value, nil
+	return nodes[0]
 }
 
 func normalizeNumericValue(value string, thousandsSeparator string, decimalSeparator string) (float64, error) {
@@ -96,7 +375,7 @@ func normalizeNumericValue(value string, thousandsSeparator string, decimalSepar
 
 	floatValue, err := strconv.ParseFloat(value, 64)
 	if err != nil {
-		return 0, fmt.Errorf("error parsing value %s to a float. error: %s", value, err)
+		return 0, newScrapeError(failureReasonNumberFormat, "error parsing value %s to a float. error: %s", value, err)
 	}
 
 	return floatValue, nil
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// reasons used to categorize scrape failures in the `reason` label
+const (
+	failureReasonRequest      = "request"
+	failureReasonAuth         = "auth"
+	failureReasonTLS          = "tls"
+	failureReasonHTTPStatus   = "http_status"
+	failureReasonStep         = "step"
+	failureReasonParse        = "parse"
+	failureReasonXPath        = "xpath"
+	failureReasonTransform    = "transform"
+	failureReasonValueMapping = "value_mapping"
+	failureReasonNumberFormat = "number_format"
+	failureReasonExpression   = "expression"
+	failureReasonMetric       = "metric"
+	failureReasonUnknown      = "unknown"
+)
+
+// scrapeError is an error that happened while scraping a target, along with the reason of the failure
+type scrapeError struct {
+	reason string
+	err    error
+}
+
+func newScrapeError(reason string, format string, a ...interface{}) error {
+	return scrapeError{reason: reason, err: fmt.Errorf(format, a...)}
+}
+
+func (e scrapeError) Error() string {
+	return e.err.Error()
+}
+
+func (e scrapeError) Unwrap() error {
+	return e.err
+}
+
+func getFailureReason(err error) string {
+	var scrapeErr scrapeError
+
+	if errors.As(err, &scrapeErr) {
+		return scrapeErr.reason
+	}
+
+	return failureReasonUnknown
+}
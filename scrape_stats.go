@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// phases of a scrape, exported in the `phase` label of the probe duration metric
+const (
+	phaseDNS      = "dns"
+	phaseConnect  = "connect"
+	phaseTLS      = "tls"
+	phaseTTFB     = "ttfb"
+	phaseDownload = "download"
+	phaseParse    = "parse"
+	phaseXPath    = "xpath"
+)
+
+var scrapePhases = []string{phaseDNS, phaseConnect, phaseTLS, phaseTTFB, phaseDownload, phaseParse, phaseXPath}
+
+// scrapeStats holds information about a scrape, exported along with the scraped metrics
+type scrapeStats struct {
+	mutex          sync.Mutex
+	phaseDurations map[string]time.Duration
+	statusCode     int
+	bodySize       int
+	// expiry of the earliest expiring certificate presented by the target, zero for plain HTTP
+	certExpiry time.Time
+	// number of nodes matched by the selector of each metric, by metric name
+	matchedNodes map[string]int
+}
+
+func newScrapeStats() *scrapeStats {
+	return &scrapeStats{
+		phaseDurations: make(map[string]time.Duration, len(scrapePhases)),
+		matchedNodes:   make(map[string]int),
+	}
+}
+
+// addPhaseDuration adds to the phase duration, since phases like DNS resolution may happen more than once with redirects
+func (s *scrapeStats) addPhaseDuration(phase string, duration time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.phaseDurations[phase] += duration
+}
+
+func (s *scrapeStats) getPhaseDuration(phase string) time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.phaseDurations[phase]
+}
+
+// clientTrace returns hooks to measure the duration of each phase of an HTTP request
+func (s *scrapeStats) clientTrace() *httptrace.ClientTrace {
+	var (
+		mutex                            sync.Mutex
+		dnsStart, tlsStart, wroteRequest time.Time
+		connectStarts                    = make(map[string]time.Time)
+	)
+
+	return &httptrace.ClientTrace{
+		DNSStart: func(_ httptrace.DNSStartInfo) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			dnsStart = time.Now()
+		},
+		DNSDone: func(_ httptrace.DNSDoneInfo) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			s.addPhaseDuration(phaseDNS, time.Since(dnsStart))
+		},
+		// connections to more than one address may be attempted in parallel
+		ConnectStart: func(network, addr string) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			connectStarts[network+addr] = time.Now()
+		},
+		ConnectDone: func(network, addr string, _ error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			s.addPhaseDuration(phaseConnect, time.Since(connectStarts[network+addr]))
+		},
+		TLSHandshakeStart: func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+			tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, _ error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			s.addPhaseDuration(phaseTLS, time.Since(tlsStart))
+		},
+		WroteRequest: func(_ httptrace.WroteRequestInfo) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+			s.addPhaseDuration(phaseTTFB, time.Since(wroteRequest))
+		},
+	}
+}
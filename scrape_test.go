@@ -2,11 +2,14 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
 	log "github.com/sirupsen/logrus"
@@ -49,7 +52,7 @@ func TestDoRequest(t *testing.T) {
 
 	server := getTestServer(response)
 
-	output, err := doRequest(server.URL)
+	output, err := doRequest(context.Background(), types.ScrapeConfig{Address: server.URL}, newScrapeStats())
 	ok(t, err)
 
 	buffer, err := io.ReadAll(output)
@@ -62,7 +65,7 @@ func TestDoRequest(t *testing.T) {
 
 func TestDoRequest_invalidRequest(t *testing.T) {
 	// invalid URL escaping makes http.NewRequest's validation to fail
-	_, err := doRequest("http://go%Qdev")
+	_, err := doRequest(context.Background(), types.ScrapeConfig{Address: "http://go%Qdev"}, newScrapeStats())
 	assert(t, err != nil, "expected doRequest to return an error on an invalid URL")
 }
 
@@ -74,7 +77,7 @@ func TestDoRequest_erroredRequest(t *testing.T) {
 		http.Redirect(w, r, "foobar://go.dev", http.StatusTemporaryRedirect)
 	}))
 
-	_, err := doRequest(server.URL)
+	_, err := doRequest(context.Background(), types.ScrapeConfig{Address: server.URL}, newScrapeStats())
 	assert(t, err != nil, "expected doRequest to return an error when the request fails")
 }
 
@@ -83,33 +86,110 @@ func TestDoRequest_erroredResponse(t *testing.T) {
 		http.Error(w, "Server error :(", 500)
 	}))
 
-	_, err := doRequest(server.URL)
+	_, err := doRequest(context.Background(), types.ScrapeConfig{Address: server.URL}, newScrapeStats())
 	assert(t, err != nil, "expected doRequest to return an error when the server responds with an error")
 }
 
+func TestDoRequest_requestOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s %s", r.Method, r.Header.Get("X-Foo"), body)
+	}))
+
+	config := types.ScrapeConfig{
+		Address: server.URL,
+		Method:  http.MethodPost,
+		Headers: map[string]string{"X-Foo": "bar"},
+		Body:    "q=foobar",
+	}
+
+	output, err := doRequest(context.Background(), config, newScrapeStats())
+	ok(t, err)
+
+	buffer, err := io.ReadAll(output)
+	ok(t, err)
+
+	equals(t, "POST bar q=foobar", string(buffer))
+}
+
+func TestDoRequest_redirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/final" {
+			fmt.Fprint(w, "final")
+			return
+		}
+
+		http.Redirect(w, r, "/final", http.StatusFound)
+	}))
+
+	config := types.ScrapeConfig{Address: server.URL + "/redirect"}
+	stats := newScrapeStats()
+
+	_, err := doRequest(context.Background(), config, stats)
+	ok(t, err)
+	equals(t, http.StatusOK, stats.statusCode)
+
+	followRedirects := false
+	config.FollowRedirects = &followRedirects
+	stats = newScrapeStats()
+
+	_, err = doRequest(context.Background(), config, stats)
+	ok(t, err)
+	equals(t, http.StatusFound, stats.statusCode)
+}
+
+func TestDoRequest_maxRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	}))
+
+	config := types.ScrapeConfig{Address: server.URL, MaxRedirects: 2}
+
+	_, err := doRequest(context.Background(), config, newScrapeStats())
+	errorContains(t, err, "stopped after 2 redirects")
+}
+
+func TestScrape_timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprint(w, "<div id=\"foobar\">1</div>")
+	}))
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Timeout = 50 * time.Millisecond
+
+	_, _, err := scrape(context.Background(), config)
+	errorContains(t, err, "deadline exceeded")
+	equals(t, failureReasonRequest, getFailureReason(err))
+}
+
 func TestParseSelector(t *testing.T) {
 	expected := "Hello world"
-	reader := io.NopCloser(strings.NewReader("<html><body><div id=\"foobar\">Hello world</div></body></html>"))
+	doc, err := parseDocument(strings.NewReader("<html><body><div id=\"foobar\">Hello world</div></body></html>"))
+	ok(t, err)
 
-	output, err := parseSelector(reader, "//div[@id='foobar']/text()")
+	output, err := parseSelector(doc, "//div[@id='foobar']/text()", selectorTypeXPath)
 	ok(t, err)
 
 	assert(t, output == expected, "expected \"Hello world\" text selected by the XPath expression, got: %s", output)
 }
 
 func TestParseSelector_invalidXPath(t *testing.T) {
-	reader := io.NopCloser(strings.NewReader("<html></html>"))
+	doc, err := parseDocument(strings.NewReader("<html></html>"))
+	ok(t, err)
 
-	_, err := parseSelector(reader, "/`$/")
+	_, err = parseSelector(doc, "/`$/", selectorTypeXPath)
 	assert(t, err != nil, "expected error for an invalid XPath expression")
 
 	errorContains(t, err, "querying the XPath")
 }
 
 func TestParseSelector_emptyElements(t *testing.T) {
-	reader := io.NopCloser(strings.NewReader("<html></html>"))
+	doc, err := parseDocument(strings.NewReader("<html></html>"))
+	ok(t, err)
 
-	_, err := parseSelector(reader, "//div")
+	_, err = parseSelector(doc, "//div", selectorTypeXPath)
 	assert(t, err != nil, "expected error when no elements were returned by the XPath query")
 }
 
@@ -119,8 +199,9 @@ func TestParseSelector_warnsMoreThanOneElement(t *testing.T) {
 	var buf bytes.Buffer
 	log.SetOutput(&buf)
 
-	reader := io.NopCloser(strings.NewReader("<html><div></div><div></div></html>"))
-	_, _ = parseSelector(reader, "//div")
+	doc, err := parseDocument(strings.NewReader("<html><div></div><div></div></html>"))
+	ok(t, err)
+	_, _ = parseSelector(doc, "//div", selectorTypeXPath)
 
 	logOutput := buf.String()
 	isWarningLog := strings.Contains(logOutput, "\"level\":\"warning\"")
@@ -143,15 +224,106 @@ func TestScrape(t *testing.T) {
 	config := testScrapeConfig
 	config.Address = server.URL
 
-	output, err := scrape(config)
+	output, _, err := scrape(context.Background(), config)
 	ok(t, err)
 
-	assert(t, output == expected, "expected scrape value to be equal to %0.2f, got %0.2f", expected, output)
+	assert(t, output[0].value == expected, "expected scrape value to be equal to %0.2f, got %0.2f", expected, output[0].value)
 }
 
-func TestScrape_errorOnDivToNumber(t *testing.T) {
+func TestScrape_multipleMetrics(t *testing.T) {
+	html := "<div id=\"foo\">1,234</div><div id=\"bar\">5.678,9</div>"
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintln(w, html)
+	}))
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Metrics = []types.MetricConfig{
+		{Name: "foo", Selector: "//div[@id='foo']/text()"},
+		{Name: "bar", Selector: "//div[@id='bar']/text()", DecimalPointSeparator: ",", ThousandsSeparator: "."},
+	}
+
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+
+	equals(t, 1, requests)
+	equals(t, 2, len(output))
+	equals(t, "foo", output[0].metricConfig.Name)
+	equals(t, 1234.0, output[0].value)
+	equals(t, "bar", output[1].metricConfig.Name)
+	equals(t, 5678.9, output[1].value)
+}
+
+func TestScrape_labelSelectors(t *testing.T) {
+	html := `<table>
+		<tr><td>foo</td><td>1,234</td></tr>
+		<tr><td>bar</td><td>5</td></tr>
+	</table>`
+
+	server := getTestServer(html)
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Metrics = []types.MetricConfig{{
+		Name:           "service_requests",
+		Selector:       "//tr/td[2]/text()",
+		Labels:         map[string]string{"env": "prod"},
+		LabelSelectors: map[string]string{"service": "../../td[1]/text()"},
+	}}
+
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+
+	equals(t, 2, len(output))
+	equals(t, 1234.0, output[0].value)
+	equals(t, map[string]string{"env": "prod", "service": "foo"}, output[0].labels)
+	equals(t, 5.0, output[1].value)
+	equals(t, map[string]string{"env": "prod", "service": "bar"}, output[1].labels)
+}
+
+func TestScrape_valueSelector(t *testing.T) {
+	html := "<table><tr><td>foo</td><td>1,234</td></tr><tr><td>bar</td><td>5</td></tr></table>"
+	server := getTestServer(html)
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Metrics = []types.MetricConfig{{
+		Name:           "service_requests",
+		Selector:       "//tr",
+		ValueSelector:  "td[2]/text()",
+		LabelSelectors: map[string]string{"service": "td[1]/text()"},
+	}}
+
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+
+	equals(t, 2, len(output))
+	equals(t, 1234.0, output[0].value)
+	equals(t, map[string]string{"service": "foo"}, output[0].labels)
+	equals(t, 5.0, output[1].value)
+	equals(t, map[string]string{"service": "bar"}, output[1].labels)
+}
+
+func TestScrape_labelSelectorsNoMatch(t *testing.T) {
+	server := getTestServer("<div id=\"foobar\">1</div>")
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Metrics = []types.MetricConfig{{
+		Name:           "foo",
+		LabelSelectors: map[string]string{"service": "../span/text()"},
+	}}
+
+	_, _, err := scrape(context.Background(), config)
+	errorContains(t, err, "error extracting label \"service\"")
+}
+
+func TestScrape_elementText(t *testing.T) {
 	html := "<div id=\"foobar\">1,234,567.08</div>"
-	// this xpath expression has no `/text()` function, so it will return element name ("div")
+	// this xpath expression has no `/text()` function, so the text of the element is used
 	xpath := "//div[@id='foobar']"
 
 	server := getTestServer(html)
@@ -160,8 +332,9 @@ func TestScrape_errorOnDivToNumber(t *testing.T) {
 	config.Selector = xpath
 	config.Address = server.URL
 
-	_, err := scrape(config)
-	errorContains(t, err, "parsing value")
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+	equals(t, 1234567.08, output[0].value)
 }
 
 func TestScrape_invalidRequest(t *testing.T) {
@@ -169,10 +342,64 @@ func TestScrape_invalidRequest(t *testing.T) {
 	config := testScrapeConfig
 	config.Address = "http://go%Qdev"
 
-	_, err := scrape(config)
+	_, _, err := scrape(context.Background(), config)
 	assert(t, err != nil, "expected scrape to return an error when the HTTP request fails")
 }
 
+func TestScrape_stats(t *testing.T) {
+	html := "<div id=\"foobar\">1,234,567.08</div>"
+	server := getTestServer(html)
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Metrics = []types.MetricConfig{
+		{Name: "foo", Selector: "//div/text()"},
+		{Name: "bar", Selector: "//span/text()"},
+	}
+
+	_, stats, err := scrape(context.Background(), config)
+	errorContains(t, err, "no elements returned")
+
+	equals(t, http.StatusOK, stats.statusCode)
+	// the test server appends a line break to the response
+	equals(t, len(html)+1, stats.bodySize)
+	equals(t, map[string]int{"foo": 1, "bar": 0}, stats.matchedNodes)
+	assert(t, stats.getPhaseDuration(phaseTTFB) > 0, "expected the time to first byte to be measured")
+}
+
+func TestScrape_failureReasons(t *testing.T) {
+	server := getTestServer("<div id=\"foobar\">foo</div>")
+	errorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Server error :(", 500)
+	}))
+
+	tests := []struct {
+		address  string
+		selector string
+		reason   string
+	}{
+		{"http://go%Qdev", testScrapeConfig.Selector, failureReasonRequest},
+		{errorServer.URL, testScrapeConfig.Selector, failureReasonHTTPStatus},
+		{server.URL, "/^$/", failureReasonXPath},
+		{server.URL, "//span/text()", failureReasonXPath},
+		{server.URL, testScrapeConfig.Selector, failureReasonNumberFormat},
+	}
+
+	for _, test := range tests {
+		config := testScrapeConfig
+		config.Address = test.address
+		config.Selector = test.selector
+
+		_, _, err := scrape(context.Background(), config)
+		assert(t, err != nil, "expected scrape of %s with selector %s to fail", test.address, test.selector)
+		equals(t, test.reason, getFailureReason(err))
+	}
+}
+
+func TestGetFailureReason_unknownError(t *testing.T) {
+	equals(t, failureReasonUnknown, getFailureReason(fmt.Errorf("foobar")))
+}
+
 func TestScrape_invalidXPath(t *testing.T) {
 	html := ""
 	xpath := "/^$/"
@@ -183,6 +410,6 @@ func TestScrape_invalidXPath(t *testing.T) {
 	config.Selector = xpath
 	config.Address = server.URL
 
-	_, err := scrape(config)
+	_, _, err := scrape(context.Background(), config)
 	errorContains(t, err, "querying the XPath")
 }
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
@@ -26,21 +29,98 @@ func getExporterMetricsRegistry() (*prometheus.Registry, error) {
 		return nil, fmt.Errorf("error registering Go collector: %s", err.Error())
 	}
 
+	for _, exporterMetric := range exporterMetrics {
+		err = metricRegistry.Register(exporterMetric)
+		if err != nil {
+			return nil, fmt.Errorf("error registering exporter metrics: %s", err.Error())
+		}
+	}
+
 	return metricRegistry, nil
 }
 
+// newHTTPServer returns the server of the exporter endpoints. it has no write timeout, since probes are already bounded
+// by the timeouts of their scrape configs and by the Prometheus scrape timeout, and a write deadline shorter than them
+// would close the connection of a slow probe instead of reporting its failure
+func newHTTPServer(port int, handler http.Handler) *http.Server {
+	return &http.Server{
+		ReadTimeout: 1 * time.Second,
+		Addr:        fmt.Sprintf(":%d", port),
+		Handler:     handler,
+	}
+}
+
 func probeHandler(w http.ResponseWriter, r *http.Request, config types.ExporterConfig) {
-	// @TODO: gather some configs from query parameters, passed from Prometheus
 	start := time.Now()
 
-	collector := collector{config: config}
+	scrapeConfigs, err := getProbeScrapeConfigs(config, r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel, err := getProbeContext(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer cancel()
+
+	collector := collector{ctx: ctx, scrapeConfigs: scrapeConfigs, globalConfig: config.GlobalConfig}
 	registry := prometheus.NewPedanticRegistry()
-	registry.MustRegister(collector)
+
+	err = registry.Register(collector)
+	if err != nil {
+		log.Errorf("error registering the metrics of the probe: %s", err)
+		http.Error(w, fmt.Sprintf("error registering the metrics of the probe: %s", err), http.StatusInternalServerError)
+		return
+	}
 
 	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
 	h.ServeHTTP(w, r)
 
 	duration := time.Since(start).Seconds()
-	// @TODO: expose metrics about duration
-	log.Debugf("scrape of endpoint %s finished in %0.2f seconds", config.ScrapeConfig.Address, duration)
+	log.Debugf("scrape of %d target(s) finished in %0.2f seconds", len(scrapeConfigs), duration)
+}
+
+// scrapeTimeoutOffset is subtracted from the Prometheus scrape timeout, leaving time for the response to be sent
+const scrapeTimeoutOffset = 500 * time.Millisecond
+
+// getProbeContext returns the request context, bounded by the scrape timeout sent by Prometheus if any
+func getProbeContext(r *http.Request) (context.Context, context.CancelFunc, error) {
+	header := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds")
+	if header == "" {
+		ctx, cancel := context.WithCancel(r.Context())
+		return ctx, cancel, nil
+	}
+
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid X-Prometheus-Scrape-Timeout-Seconds header %q: %s", header, err)
+	}
+
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout > scrapeTimeoutOffset {
+		timeout -= scrapeTimeoutOffset
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	return ctx, cancel, nil
+}
+
+// getProbeScrapeConfigs selects the scrape configs of a probe from the `module` and `target` query parameters
+func getProbeScrapeConfigs(config types.ExporterConfig, query url.Values) ([]types.ScrapeConfig, error) {
+	target := query.Get("target")
+	module := query.Get("module")
+
+	if module == "" {
+		return getScrapeConfigs(config, target)
+	}
+
+	scrapeConfig, err := getModuleScrapeConfig(config, module, target)
+	if err != nil {
+		return nil, err
+	}
+
+	return []types.ScrapeConfig{scrapeConfig}, nil
 }
@@ -1,9 +1,16 @@
 package main
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestGetExporterMetricsRegistry(t *testing.T) {
@@ -12,6 +19,18 @@ func TestGetExporterMetricsRegistry(t *testing.T) {
 	assert(t, registry != nil, "metric registry should not be null")
 }
 
+func TestGetExporterMetricsRegistry_exporterMetrics(t *testing.T) {
+	registry, err := getExporterMetricsRegistry()
+	ok(t, err)
+
+	setConfigReloadStatus(true)
+	probesTotal.WithLabelValues("foo").Inc()
+
+	count, err := testutil.GatherAndCount(registry, "htmlexporter_probes_total", "htmlexporter_config_last_reload_successful")
+	ok(t, err)
+	assert(t, count >= 2, "expected exporter metrics to be registered, got %d series", count)
+}
+
 func TestProbeHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/probe", nil)
 
@@ -20,6 +39,7 @@ func TestProbeHandler(t *testing.T) {
 	config := testExporterConfig
 	server := getTestServer("<div id=\"foobar\">1</div>")
 	config.ScrapeConfig.Address = server.URL
+	config.ScrapeConfigs = []types.ScrapeConfig{config.ScrapeConfig}
 
 	rr := httptest.NewRecorder()
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -31,3 +51,153 @@ func TestProbeHandler(t *testing.T) {
 	assert(t, rr.Code == http.StatusOK, "response should be of HTTP %d status, got %d", http.StatusOK, rr.Code)
 	assert(t, rr.Body.String() != "", "response body should not be empty")
 }
+
+func TestProbeHandler_target(t *testing.T) {
+	server := getTestServer("<div id=\"foobar\">1</div>")
+
+	scrapeConfig := testExporterConfig.ScrapeConfig
+	scrapeConfig.Name = "foo"
+	scrapeConfig.Address = server.URL
+
+	otherScrapeConfig := scrapeConfig
+	otherScrapeConfig.Name = "bar"
+	otherScrapeConfig.MetricConfig.Name = "other_metric"
+
+	config := testExporterConfig
+	config.ScrapeConfigs = []types.ScrapeConfig{scrapeConfig, otherScrapeConfig}
+
+	req, err := http.NewRequest("GET", "/probe?target=bar", nil)
+	ok(t, err)
+
+	rr := httptest.NewRecorder()
+	probeHandler(rr, req, config)
+
+	body := rr.Body.String()
+	assert(t, rr.Code == http.StatusOK, "response should be of HTTP %d status, got %d", http.StatusOK, rr.Code)
+	assert(t, strings.Contains(body, "htmlexporter_other_metric"), "response should contain the metric of the requested target. body: %s", body)
+	assert(t, !strings.Contains(body, "htmlexporter_wikipedia_articles_total"), "response should not contain metrics of other targets. body: %s", body)
+}
+
+func TestProbeHandler_unknownTarget(t *testing.T) {
+	req, err := http.NewRequest("GET", "/probe?target=foobar", nil)
+	ok(t, err)
+
+	rr := httptest.NewRecorder()
+	probeHandler(rr, req, testExporterConfig)
+
+	assert(t, rr.Code == http.StatusBadRequest, "response should be of HTTP %d status, got %d", http.StatusBadRequest, rr.Code)
+}
+
+func TestProbeHandler_module(t *testing.T) {
+	server := getTestServer("<div id=\"foobar\">1</div>")
+
+	config := testExporterConfig
+	config.Modules = map[string]types.ScrapeConfig{"foo": testExporterConfig.ScrapeConfig}
+
+	req, err := http.NewRequest("GET", "/probe?module=foo&target="+url.QueryEscape(server.URL), nil)
+	ok(t, err)
+
+	rr := httptest.NewRecorder()
+	probeHandler(rr, req, config)
+
+	body := rr.Body.String()
+	assert(t, rr.Code == http.StatusOK, "response should be of HTTP %d status, got %d", http.StatusOK, rr.Code)
+	assert(t, strings.Contains(body, "htmlexporter_wikipedia_articles_total"), "response should contain the module metric. body: %s", body)
+}
+
+func TestProbeHandler_unknownModule(t *testing.T) {
+	req, err := http.NewRequest("GET", "/probe?module=foobar&target=http://foo.dev", nil)
+	ok(t, err)
+
+	rr := httptest.NewRecorder()
+	probeHandler(rr, req, testExporterConfig)
+
+	assert(t, rr.Code == http.StatusBadRequest, "response should be of HTTP %d status, got %d", http.StatusBadRequest, rr.Code)
+}
+
+func TestProbeHandler_invalidMetricName(t *testing.T) {
+	server := getTestServer("<div id=\"foobar\">1</div>")
+
+	config := testExporterConfig
+	config.ScrapeConfig.Address = server.URL
+	config.ScrapeConfig.MetricConfig.Name = "foo-bar"
+	config.ScrapeConfigs = []types.ScrapeConfig{config.ScrapeConfig}
+
+	req, err := http.NewRequest("GET", "/probe", nil)
+	ok(t, err)
+
+	rr := httptest.NewRecorder()
+	probeHandler(rr, req, config)
+
+	assert(t, rr.Code == http.StatusInternalServerError, "response should be of HTTP %d status, got %d", http.StatusInternalServerError, rr.Code)
+	assert(t, strings.Contains(rr.Body.String(), "error registering the metrics of the probe"), "unexpected response body: %s", rr.Body.String())
+}
+
+func TestProbeHandler_slowTarget(t *testing.T) {
+	release := make(chan struct{})
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer target.Close()
+	defer close(release)
+
+	config := testExporterConfig
+	config.ScrapeConfig.Name = "foo"
+	config.ScrapeConfig.Address = target.URL
+	config.ScrapeConfig.Timeout = time.Second
+	config.ScrapeConfigs = []types.ScrapeConfig{config.ScrapeConfig}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/probe", func(w http.ResponseWriter, r *http.Request) {
+		probeHandler(w, r, config)
+	})
+
+	// the exporter server is used as is, so its timeouts apply to the probe
+	server := httptest.NewUnstartedServer(mux)
+	server.Config = newHTTPServer(0, mux)
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/probe")
+	ok(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	ok(t, err)
+
+	equals(t, http.StatusOK, resp.StatusCode)
+	assert(t, strings.Contains(string(body), `probe_success{target="foo"} 0`), "response should report the failed probe. body: %s", body)
+	assert(t, strings.Contains(string(body), `probe_failure_reason{reason="request",target="foo"} 1`), "response should report the failure reason. body: %s", body)
+}
+
+func TestGetProbeContext(t *testing.T) {
+	req, err := http.NewRequest("GET", "/probe", nil)
+	ok(t, err)
+
+	ctx, cancel, err := getProbeContext(req)
+	ok(t, err)
+	defer cancel()
+
+	_, hasDeadline := ctx.Deadline()
+	assert(t, !hasDeadline, "expected no deadline without the scrape timeout header")
+
+	req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "10")
+	ctx, cancel, err = getProbeContext(req)
+	ok(t, err)
+	defer cancel()
+
+	deadline, hasDeadline := ctx.Deadline()
+	assert(t, hasDeadline, "expected a deadline with the scrape timeout header")
+	assert(t, time.Until(deadline) <= 10*time.Second-scrapeTimeoutOffset, "expected the deadline to be before the scrape timeout minus the offset, got %s", time.Until(deadline))
+}
+
+func TestProbeHandler_invalidScrapeTimeout(t *testing.T) {
+	req, err := http.NewRequest("GET", "/probe", nil)
+	ok(t, err)
+	req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "foo")
+
+	rr := httptest.NewRecorder()
+	probeHandler(rr, req, testExporterConfig)
+
+	assert(t, rr.Code == http.StatusBadRequest, "response should be of HTTP %d status, got %d", http.StatusBadRequest, rr.Code)
+}
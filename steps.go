@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// runSteps requests each step of the scrape config in order, extracting variables that are templated into
+// the address and headers of the following steps. it returns the scrape config with the variables applied
+func runSteps(ctx context.Context, config types.ScrapeConfig, stats *scrapeStats) (types.ScrapeConfig, error) {
+	variables := make(map[string]string)
+	baseURL := ""
+
+	for i, step := range config.Steps {
+		stepConfig := config
+		stepConfig.Method = http.MethodGet
+		stepConfig.Body = ""
+		// headers of the scrape config may reference variables not yet extracted, so steps only send their own
+		stepConfig.Headers = step.Headers
+		stepConfig.Address = step.Address
+
+		stepConfig, err := applyStepVariables(stepConfig, variables, baseURL)
+		if err != nil {
+			return types.ScrapeConfig{}, err
+		}
+
+		log.Debugf("requesting step %d of target \"%s\" at URL '%s'", i+1, config.Name, redactURL(stepConfig.Address))
+		body, err := doRequest(ctx, stepConfig, stats)
+		if err != nil {
+			return types.ScrapeConfig{}, err
+		}
+
+		doc, err := parseDocument(body)
+		body.Close()
+		if err != nil {
+			return types.ScrapeConfig{}, err
+		}
+
+		for name, selector := range step.Variables {
+			nodes, err := queryNodes(doc, selector, config.SelectorType)
+			if err != nil {
+				return types.ScrapeConfig{}, err
+			}
+
+			variables[name] = strings.TrimSpace(getNodeText(nodes[0]))
+			log.Debugf("extracted variable %s with value '%s'", name, variables[name])
+		}
+
+		baseURL = stepConfig.Address
+	}
+
+	return applyStepVariables(config, variables, baseURL)
+}
+
+// applyStepVariables templates the variables into the address, headers and body of the config.
+// relative addresses are resolved against the address of the previous step
+func applyStepVariables(config types.ScrapeConfig, variables map[string]string, baseURL string) (types.ScrapeConfig, error) {
+	address, err := executeStepTemplate(config.Address, variables)
+	if err != nil {
+		return types.ScrapeConfig{}, err
+	}
+
+	if baseURL != "" {
+		address, err = resolveURL(baseURL, address)
+		if err != nil {
+			return types.ScrapeConfig{}, err
+		}
+	}
+
+	headers := make(map[string]string, len(config.Headers))
+
+	for name, value := range config.Headers {
+		headers[name], err = executeStepTemplate(value, variables)
+		if err != nil {
+			return types.ScrapeConfig{}, err
+		}
+	}
+
+	body, err := executeStepTemplate(config.Body, variables)
+	if err != nil {
+		return types.ScrapeConfig{}, err
+	}
+
+	config.Address = address
+	config.Headers = headers
+	config.Body = body
+
+	return config, nil
+}
+
+func executeStepTemplate(text string, variables map[string]string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New("step").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", newScrapeError(failureReasonStep, "error parsing template `%s`. error: %s", text, err)
+	}
+
+	var buffer bytes.Buffer
+
+	err = tmpl.Execute(&buffer, variables)
+	if err != nil {
+		return "", newScrapeError(failureReasonStep, "error executing template `%s`. error: %s", text, err)
+	}
+
+	return buffer.String(), nil
+}
+
+func resolveURL(baseURL string, address string) (string, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return "", newScrapeError(failureReasonStep, "invalid step URL %s. error: %s", redactURL(baseURL), err)
+	}
+
+	reference, err := url.Parse(address)
+	if err != nil {
+		return "", newScrapeError(failureReasonStep, "invalid URL %s. error: %s", redactURL(address), err)
+	}
+
+	return base.ResolveReference(reference).String(), nil
+}
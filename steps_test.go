@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+)
+
+func getTestStepsServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "<a id=\"latest\" href=\"/reports/2021-10\">Latest report</a><span id=\"token\">abc</span>")
+	})
+	mux.HandleFunc("/reports/2021-10", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "abc" {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		fmt.Fprintln(w, "<div id=\"foobar\">1,234</div>")
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestScrape_steps(t *testing.T) {
+	server := getTestStepsServer()
+
+	config := testScrapeConfig
+	config.Steps = []types.StepConfig{{
+		Address: server.URL,
+		Variables: map[string]string{
+			"report_url": "//a[@id='latest']/@href",
+			"token":      "//span[@id='token']",
+		},
+	}}
+	config.Address = "{{ .report_url }}"
+	config.Headers = map[string]string{"X-Token": "{{ .token }}"}
+
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+
+	equals(t, 1234.0, output[0].value)
+}
+
+func TestScrape_stepsMissingVariable(t *testing.T) {
+	server := getTestStepsServer()
+
+	config := testScrapeConfig
+	config.Steps = []types.StepConfig{{Address: server.URL}}
+	config.Address = "{{ .report_url }}"
+
+	_, _, err := scrape(context.Background(), config)
+	errorContains(t, err, "error executing template")
+	equals(t, failureReasonStep, getFailureReason(err))
+}
+
+func TestScrape_stepsVariableNotFound(t *testing.T) {
+	server := getTestStepsServer()
+
+	config := testScrapeConfig
+	config.Steps = []types.StepConfig{{
+		Address:   server.URL,
+		Variables: map[string]string{"report_url": "//a[@id='oldest']/@href"},
+	}}
+	config.Address = "{{ .report_url }}"
+
+	_, _, err := scrape(context.Background(), config)
+	equals(t, failureReasonXPath, getFailureReason(err))
+}
+
+func TestApplyStepVariables(t *testing.T) {
+	config := types.ScrapeConfig{
+		Address: "../{{ .page }}?id={{ .id }}",
+		Headers: map[string]string{"X-Id": "{{ .id }}", "Accept": "text/html"},
+		Body:    "id={{ .id }}",
+	}
+	variables := map[string]string{"page": "status", "id": "42"}
+
+	output, err := applyStepVariables(config, variables, "https://foo.dev/reports/list")
+	ok(t, err)
+
+	equals(t, "https://foo.dev/status?id=42", output.Address)
+	equals(t, map[string]string{"X-Id": "42", "Accept": "text/html"}, output.Headers)
+	equals(t, "id=42", output.Body)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+	"github.com/antchfx/htmlquery"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
+)
+
+// tableColumnLabel is the label holding the header of the column a table value was taken from
+const tableColumnLabel = "column"
+
+// scrapeTable exports one sample per row and value column of the table matched by the metric selector
+func scrapeTable(table *html.Node, metricConfig types.MetricConfig) ([]metricValue, error) {
+	tableConfig := metricConfig.Table
+
+	rows := htmlquery.Find(table, ".//tr")
+	if len(rows) < 2 {
+		return nil, newScrapeError(failureReasonXPath, "table matched by the XPath expression `%s` has no data rows", metricConfig.Selector)
+	}
+
+	headers := getTableRowCells(rows[0])
+	columnIndexes := make(map[string]int, len(headers))
+
+	for i, header := range headers {
+		columnIndexes[header] = i
+	}
+
+	for _, column := range append(getLabelValues(tableConfig.LabelColumns), tableConfig.ValueColumns...) {
+		if _, found := columnIndexes[column]; !found {
+			return nil, newScrapeError(failureReasonXPath, "column \"%s\" not found in the table headers %q", column, headers)
+		}
+	}
+
+	var metricValues []metricValue
+
+	for _, row := range rows[1:] {
+		cells := getTableRowCells(row)
+
+		if len(cells) != len(headers) {
+			log.Debugf("skipping table row with %d cells, expected %d", len(cells), len(headers))
+			continue
+		}
+
+		labels := make(map[string]string, len(metricConfig.Labels)+len(tableConfig.LabelColumns)+1)
+
+		for name, value := range metricConfig.Labels {
+			labels[name] = value
+		}
+
+		for name, column := range tableConfig.LabelColumns {
+			labels[name] = cells[columnIndexes[column]]
+		}
+
+		for _, column := range tableConfig.ValueColumns {
+			valueLabels := make(map[string]string, len(labels)+1)
+			for name, value := range labels {
+				valueLabels[name] = value
+			}
+			valueLabels[tableColumnLabel] = column
+
+			values, err := makeMetricValues(cells[columnIndexes[column]], metricConfig, valueLabels)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing column \"%s\". %w", column, err)
+			}
+
+			metricValues = append(metricValues, values...)
+		}
+	}
+
+	return metricValues, nil
+}
+
+func getTableRowCells(row *html.Node) []string {
+	cellNodes := htmlquery.Find(row, "./th|./td")
+	cells := make([]string, len(cellNodes))
+
+	for i, cellNode := range cellNodes {
+		cells[i] = strings.TrimSpace(htmlquery.InnerText(cellNode))
+	}
+
+	return cells
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+	"github.com/antchfx/htmlquery"
+)
+
+var testTableHTML = `<table id="services">
+	<tr><th>Service</th><th>Region</th><th>Requests</th><th>Errors</th></tr>
+	<tr><td>foo</td><td>us</td><td>1,234</td><td>5</td></tr>
+	<tr><td>bar</td><td>eu</td><td>567</td><td>0</td></tr>
+	<tr><td colspan="4">Updated every minute</td></tr>
+</table>`
+
+var testTableMetricConfig = types.MetricConfig{
+	Name:                  "service_stats",
+	Selector:              "//table[@id='services']",
+	DecimalPointSeparator: ".",
+	ThousandsSeparator:    ",",
+	Labels:                map[string]string{"env": "prod"},
+	Table: &types.TableConfig{
+		LabelColumns: map[string]string{"service": "Service"},
+		ValueColumns: []string{"Requests", "Errors"},
+	},
+}
+
+func TestScrapeTable(t *testing.T) {
+	doc, err := parseDocument(strings.NewReader(testTableHTML))
+	ok(t, err)
+
+	output, err := scrapeTable(htmlquery.FindOne(doc, "//table"), testTableMetricConfig)
+	ok(t, err)
+
+	equals(t, 4, len(output))
+	equals(t, 1234.0, output[0].value)
+	equals(t, map[string]string{"env": "prod", "service": "foo", "column": "Requests"}, output[0].labels)
+	equals(t, 5.0, output[1].value)
+	equals(t, map[string]string{"env": "prod", "service": "foo", "column": "Errors"}, output[1].labels)
+	equals(t, 567.0, output[2].value)
+	equals(t, map[string]string{"env": "prod", "service": "bar", "column": "Requests"}, output[2].labels)
+}
+
+func TestScrapeTable_unknownColumn(t *testing.T) {
+	doc, err := parseDocument(strings.NewReader(testTableHTML))
+	ok(t, err)
+
+	metricConfig := testTableMetricConfig
+	metricConfig.Table = &types.TableConfig{ValueColumns: []string{"Latency"}}
+
+	_, err = scrapeTable(htmlquery.FindOne(doc, "//table"), metricConfig)
+	errorContains(t, err, "column \"Latency\" not found")
+}
+
+func TestScrapeTable_invalidValue(t *testing.T) {
+	doc, err := parseDocument(strings.NewReader(testTableHTML))
+	ok(t, err)
+
+	metricConfig := testTableMetricConfig
+	metricConfig.Table = &types.TableConfig{ValueColumns: []string{"Region"}}
+
+	_, err = scrapeTable(htmlquery.FindOne(doc, "//table"), metricConfig)
+	errorContains(t, err, "error parsing column \"Region\"")
+}
+
+func TestScrapeTable_noDataRows(t *testing.T) {
+	doc, err := parseDocument(strings.NewReader("<table><tr><th>Foo</th></tr></table>"))
+	ok(t, err)
+
+	metricConfig := testTableMetricConfig
+
+	_, err = scrapeTable(htmlquery.FindOne(doc, "//table"), metricConfig)
+	errorContains(t, err, "no data rows")
+}
+
+func TestGetMetricLabelKeys_table(t *testing.T) {
+	labelKeys := getMetricLabelKeys(testTableMetricConfig)
+
+	equals(t, []string{"column", "env", "service"}, labelKeys)
+}
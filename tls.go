@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+)
+
+var tlsVersions = map[string]uint16{
+	"TLS10": tls.VersionTLS10,
+	"TLS11": tls.VersionTLS11,
+	"TLS12": tls.VersionTLS12,
+	"TLS13": tls.VersionTLS13,
+}
+
+// validateTLSConfig checks the options of a TLS config that don't depend on files, which are only read when scraping
+func validateTLSConfig(scrapeConfig types.ScrapeConfig) error {
+	if scrapeConfig.TLSConfig == nil || scrapeConfig.TLSConfig.MinVersion == "" {
+		return nil
+	}
+
+	if _, found := tlsVersions[scrapeConfig.TLSConfig.MinVersion]; !found {
+		return fmt.Errorf("unknown TLS version \"%s\" in scrape config \"%s\"", scrapeConfig.TLSConfig.MinVersion, scrapeConfig.Name)
+	}
+
+	return nil
+}
+
+// transports holds the HTTP transports of each TLS config, so connections to the targets are reused across scrapes
+var (
+	transports      = map[types.TLSConfig]*http.Transport{}
+	transportsMutex sync.Mutex
+)
+
+// getTransport returns the default HTTP transport, or a copy of it configured with the TLS config of the scrape config
+// if set. the files of the TLS config are read when its transport is first created
+func getTransport(config types.ScrapeConfig) (http.RoundTripper, error) {
+	if config.TLSConfig == nil {
+		return http.DefaultTransport, nil
+	}
+
+	transportsMutex.Lock()
+	defer transportsMutex.Unlock()
+
+	if transport, found := transports[*config.TLSConfig]; found {
+		return transport, nil
+	}
+
+	tlsConfig, err := newTLSConfig(*config.TLSConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transports[*config.TLSConfig] = transport
+
+	return transport, nil
+}
+
+// resetTransports closes the connections of the transports of the TLS configs, so their files are read again
+// by the next scrapes, e.g. after a certificate is renewed
+func resetTransports() {
+	transportsMutex.Lock()
+	defer transportsMutex.Unlock()
+
+	for tlsConfig, transport := range transports {
+		transport.CloseIdleConnections()
+		delete(transports, tlsConfig)
+	}
+}
+
+func newTLSConfig(config types.TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         config.ServerName,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	if config.MinVersion != "" {
+		minVersion, found := tlsVersions[config.MinVersion]
+		if !found {
+			return nil, fmt.Errorf("unknown TLS version %s", config.MinVersion)
+		}
+
+		tlsConfig.MinVersion = minVersion
+	}
+
+	if config.CAFile != "" {
+		caBytes, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA file %s. error: %s", config.CAFile, err)
+		}
+
+		caPool := x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(caBytes) {
+			return nil, fmt.Errorf("no valid certificates found in CA file %s", config.CAFile)
+		}
+
+		tlsConfig.RootCAs = caPool
+	}
+
+	if config.CertFile != "" || config.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate %s and key %s. error: %s", config.CertFile, config.KeyFile, err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// isTLSError reports whether a request failed because of the TLS handshake, e.g. an untrusted or expired
+// certificate, a certificate for another host or a TLS alert sent by the target
+func isTLSError(err error) bool {
+	var unknownAuthorityError x509.UnknownAuthorityError
+	var certificateInvalidError x509.CertificateInvalidError
+	var hostnameError x509.HostnameError
+	var systemRootsError x509.SystemRootsError
+	var recordHeaderError tls.RecordHeaderError
+
+	if errors.As(err, &unknownAuthorityError) || errors.As(err, &certificateInvalidError) || errors.As(err, &hostnameError) ||
+		errors.As(err, &systemRootsError) || errors.As(err, &recordHeaderError) {
+		return true
+	}
+
+	// alerts sent by the target, e.g. when it rejects the client certificate, are reported as remote errors
+	var opError *net.OpError
+	return errors.As(err, &opError) && opError.Op == "remote error"
+}
+
+// getEarliestCertExpiry returns when the first certificate of the chain presented by the target expires
+func getEarliestCertExpiry(state *tls.ConnectionState) time.Time {
+	var earliest time.Time
+
+	for _, cert := range state.PeerCertificates {
+		if earliest.IsZero() || cert.NotAfter.Before(earliest) {
+			earliest = cert.NotAfter
+		}
+	}
+
+	return earliest
+}
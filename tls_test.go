@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+)
+
+func getTestTLSServer(t *testing.T) (*httptest.Server, string) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "<div id=\"foobar\">1</div>")
+	}))
+
+	caFile := path.Join(t.TempDir(), "ca.pem")
+	caBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	ok(t, os.WriteFile(caFile, caBytes, 0600))
+
+	return server, caFile
+}
+
+func TestDoRequest_tlsUnknownAuthority(t *testing.T) {
+	server, _ := getTestTLSServer(t)
+
+	_, err := doRequest(context.Background(), types.ScrapeConfig{Address: server.URL}, newScrapeStats())
+	errorContains(t, err, "certificate")
+	equals(t, failureReasonTLS, getFailureReason(err))
+}
+
+func TestDoRequest_tlsHostnameMismatch(t *testing.T) {
+	server, caFile := getTestTLSServer(t)
+
+	config := types.ScrapeConfig{Address: server.URL, TLSConfig: &types.TLSConfig{CAFile: caFile, ServerName: "foo.dev"}}
+
+	_, err := doRequest(context.Background(), config, newScrapeStats())
+	errorContains(t, err, "foo.dev")
+	equals(t, failureReasonTLS, getFailureReason(err))
+}
+
+func TestDoRequest_connectionRefused(t *testing.T) {
+	server, _ := getTestTLSServer(t)
+	server.Close()
+
+	_, err := doRequest(context.Background(), types.ScrapeConfig{Address: server.URL}, newScrapeStats())
+	equals(t, failureReasonRequest, getFailureReason(err))
+}
+
+func TestDoRequest_tlsCAFile(t *testing.T) {
+	server, caFile := getTestTLSServer(t)
+
+	config := types.ScrapeConfig{Address: server.URL, TLSConfig: &types.TLSConfig{CAFile: caFile, MinVersion: "TLS12"}}
+	stats := newScrapeStats()
+
+	_, err := doRequest(context.Background(), config, stats)
+	ok(t, err)
+
+	equals(t, server.Certificate().NotAfter, stats.certExpiry)
+}
+
+func TestDoRequest_tlsInsecureSkipVerify(t *testing.T) {
+	server, _ := getTestTLSServer(t)
+
+	config := types.ScrapeConfig{Address: server.URL, TLSConfig: &types.TLSConfig{InsecureSkipVerify: true}}
+
+	_, err := doRequest(context.Background(), config, newScrapeStats())
+	ok(t, err)
+}
+
+func TestDoRequest_invalidTLSConfig(t *testing.T) {
+	server, _ := getTestTLSServer(t)
+
+	config := types.ScrapeConfig{Address: server.URL, TLSConfig: &types.TLSConfig{CAFile: "/does/not/exist"}}
+
+	_, err := doRequest(context.Background(), config, newScrapeStats())
+	errorContains(t, err, "unable to read CA file")
+	equals(t, failureReasonTLS, getFailureReason(err))
+}
+
+func TestNewTLSConfig_invalidMinVersion(t *testing.T) {
+	_, err := newTLSConfig(types.TLSConfig{MinVersion: "SSL3"})
+	errorContains(t, err, "unknown TLS version SSL3")
+}
+
+func TestNewTLSConfig_invalidClientCertificate(t *testing.T) {
+	_, err := newTLSConfig(types.TLSConfig{CertFile: "/does/not/exist.crt", KeyFile: "/does/not/exist.key"})
+	errorContains(t, err, "unable to load client certificate")
+}
+
+func TestParseConfig_invalidMinVersion(t *testing.T) {
+	config := []byte(`
+scrape_config:
+  name: foo
+  address: "https://foo.dev"
+  tls_config:
+    min_version: SSL3
+`)
+
+	_, err := parseConfig(config)
+	errorContains(t, err, "unknown TLS version \"SSL3\" in scrape config \"foo\"")
+}
+
+func TestGetTransport_reused(t *testing.T) {
+	_, caFile := getTestTLSServer(t)
+
+	config := types.ScrapeConfig{TLSConfig: &types.TLSConfig{CAFile: caFile}}
+
+	transport, err := getTransport(config)
+	ok(t, err)
+
+	sameTransport, err := getTransport(types.ScrapeConfig{TLSConfig: &types.TLSConfig{CAFile: caFile}})
+	ok(t, err)
+	assert(t, transport == sameTransport, "expected the transport of the same TLS config to be reused")
+
+	otherTransport, err := getTransport(types.ScrapeConfig{TLSConfig: &types.TLSConfig{CAFile: caFile, ServerName: "foo.dev"}})
+	ok(t, err)
+	assert(t, transport != otherTransport, "expected another TLS config to have its own transport")
+
+	resetTransports()
+
+	newTransport, err := getTransport(config)
+	ok(t, err)
+	assert(t, transport != newTransport, "expected a new transport after the transports are reset")
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// types of the transforms applied to scraped values before they are parsed
+const (
+	transformTrim         = "trim"
+	transformLowercase    = "lowercase"
+	transformStripPrefix  = "strip_prefix"
+	transformStripSuffix  = "strip_suffix"
+	transformRegexExtract = "regex_extract"
+	transformRegexReplace = "regex_replace"
+)
+
+var transformTypes = map[string]bool{
+	transformTrim:         true,
+	transformLowercase:    true,
+	transformStripPrefix:  true,
+	transformStripSuffix:  true,
+	transformRegexExtract: true,
+	transformRegexReplace: true,
+}
+
+// parseMetricValue applies the transforms of the metric to a scraped value, then maps it with the value mappings
+// of the metric or parses it into a number, converted to the base unit if the metric has a unit
+func parseMetricValue(value string, metricConfig types.MetricConfig) (float64, error) {
+	value, err := applyTransforms(value, metricConfig.Transforms)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(metricConfig.ValueMappings) > 0 || metricConfig.DefaultValue != nil {
+		numberValue, found, err := mapMetricValue(value, metricConfig)
+		if err != nil || found {
+			return numberValue, err
+		}
+	}
+
+	parseNumber := getNumberParser(metricConfig.Locale, metricConfig.ThousandsSeparator, metricConfig.DecimalPointSeparator)
+
+	if metricConfig.Unit != "" {
+		return normalizeUnitValue(value, metricConfig.Unit, parseNumber)
+	}
+
+	return parseNumber(value)
+}
+
+// validateTransforms checks the types, regular expressions and capture groups of the transforms of a metric,
+// compiling the regular expressions so they are ready for the scrapes
+func validateTransforms(metricConfig types.MetricConfig) error {
+	for i, transform := range metricConfig.Transforms {
+		if !transformTypes[transform.Type] {
+			return fmt.Errorf("unknown type \"%s\" in transform %d of metric \"%s\"", transform.Type, i+1, metricConfig.Name)
+		}
+
+		if transform.Type != transformRegexExtract && transform.Type != transformRegexReplace {
+			continue
+		}
+
+		pattern, err := compileRegexp(transform.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid regular expression `%s` in transform %d of metric \"%s\": %s", transform.Pattern, i+1, metricConfig.Name, err)
+		}
+
+		if transform.Type == transformRegexExtract && (transform.Group < 0 || transform.Group > pattern.NumSubexp()) {
+			return fmt.Errorf("regular expression `%s` in transform %d of metric \"%s\" has no capture group %d", transform.Pattern, i+1, metricConfig.Name, transform.Group)
+		}
+	}
+
+	return nil
+}
+
+func applyTransforms(value string, transforms []types.TransformConfig) (string, error) {
+	for i, transform := range transforms {
+		var err error
+
+		value, err = applyTransform(value, transform)
+		if err != nil {
+			return "", err
+		}
+
+		log.Debugf("transform %d (%s) output: '%s'", i+1, transform.Type, value)
+	}
+
+	return value, nil
+}
+
+func applyTransform(value string, transform types.TransformConfig) (string, error) {
+	switch transform.Type {
+	case transformTrim:
+		return strings.TrimSpace(value), nil
+	case transformLowercase:
+		return strings.ToLower(value), nil
+	case transformStripPrefix:
+		return strings.TrimPrefix(value, transform.Value), nil
+	case transformStripSuffix:
+		return strings.TrimSuffix(value, transform.Value), nil
+	case transformRegexExtract:
+		pattern, err := compileTransformPattern(transform.Pattern)
+		if err != nil {
+			return "", err
+		}
+
+		// the first capture group is used by default, or the whole match if the pattern has no groups
+		group := transform.Group
+		if group == 0 && pattern.NumSubexp() > 0 {
+			group = 1
+		}
+
+		if group < 0 || group > pattern.NumSubexp() {
+			return "", newScrapeError(failureReasonTransform, "regular expression `%s` has no capture group %d", transform.Pattern, group)
+		}
+
+		matches := pattern.FindStringSubmatch(value)
+		if matches == nil {
+			return "", newScrapeError(failureReasonTransform, "regular expression `%s` did not match the value '%s'", transform.Pattern, value)
+		}
+
+		return matches[group], nil
+	case transformRegexReplace:
+		pattern, err := compileTransformPattern(transform.Pattern)
+		if err != nil {
+			return "", err
+		}
+
+		return pattern.ReplaceAllString(value, transform.Replacement), nil
+	}
+
+	return "", newScrapeError(failureReasonTransform, "unknown transform type \"%s\"", transform.Type)
+}
+
+func compileTransformPattern(pattern string) (*regexp.Regexp, error) {
+	compiled, err := compileRegexp(pattern)
+	if err != nil {
+		return nil, newScrapeError(failureReasonTransform, "invalid regular expression `%s`. error: %s", pattern, err)
+	}
+
+	return compiled, nil
+}
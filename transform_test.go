@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+)
+
+func TestApplyTransforms(t *testing.T) {
+	transforms := []types.TransformConfig{
+		{Type: "trim"},
+		{Type: "lowercase"},
+		{Type: "regex_extract", Pattern: `users: ([0-9,]+) online`},
+		{Type: "regex_replace", Pattern: `,`, Replacement: ""},
+	}
+
+	value, err := applyTransforms("  Users: 1,234 online\n", transforms)
+	ok(t, err)
+	equals(t, "1234", value)
+}
+
+func TestApplyTransforms_stripPrefixAndSuffix(t *testing.T) {
+	transforms := []types.TransformConfig{
+		{Type: "strip_prefix", Value: "$"},
+		{Type: "strip_suffix", Value: "%"},
+	}
+
+	value, err := applyTransforms("$42%", transforms)
+	ok(t, err)
+	equals(t, "42", value)
+}
+
+func TestApplyTransforms_regexExtractGroup(t *testing.T) {
+	transforms := []types.TransformConfig{
+		{Type: "regex_extract", Pattern: `(\d+) of (\d+)`, Group: 2},
+	}
+
+	value, err := applyTransforms("3 of 10", transforms)
+	ok(t, err)
+	equals(t, "10", value)
+}
+
+func TestApplyTransforms_regexExtractWholeMatch(t *testing.T) {
+	transforms := []types.TransformConfig{
+		{Type: "regex_extract", Pattern: `\d+`},
+	}
+
+	value, err := applyTransforms("42 items", transforms)
+	ok(t, err)
+	equals(t, "42", value)
+}
+
+func TestApplyTransforms_errors(t *testing.T) {
+	tests := map[string]types.TransformConfig{
+		"did not match":              {Type: "regex_extract", Pattern: `\d+`},
+		"has no capture group 2":     {Type: "regex_extract", Pattern: `([a-z]+)`, Group: 2},
+		"invalid regular expression": {Type: "regex_replace", Pattern: `(`},
+		"unknown transform type":     {Type: "uppercase"},
+	}
+
+	for expectedError, transform := range tests {
+		_, err := applyTransforms("foobar", []types.TransformConfig{transform})
+		assert(t, err != nil, "expected transform %s to fail", transform.Type)
+		errorContains(t, err, expectedError)
+		equals(t, failureReasonTransform, getFailureReason(err))
+	}
+}
+
+func TestScrape_transforms(t *testing.T) {
+	server := getTestServer("<div id=\"foobar\">Usage: 42%</div>")
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Metrics = []types.MetricConfig{{
+		Name: "usage",
+		Transforms: []types.TransformConfig{
+			{Type: "strip_prefix", Value: "Usage: "},
+			{Type: "trim"},
+			{Type: "strip_suffix", Value: "%"},
+		},
+	}}
+
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+	equals(t, 42.0, output[0].value)
+}
+
+func TestParseConfig_invalidTransforms(t *testing.T) {
+	tests := map[string]string{
+		"unknown type \"uppercase\" in transform 1 of metric \"foo\"":     `{type: uppercase}`,
+		"invalid regular expression `(` in transform 1 of metric \"foo\"": `{type: regex_replace, pattern: "("}`,
+		"has no capture group 2": `{type: regex_extract, pattern: "([a-z]+)", group: 2}`,
+	}
+
+	for expectedError, transform := range tests {
+		config := []byte(`
+scrape_config:
+  address: "http://foo.dev"
+  metrics:
+    - name: foo
+      transforms:
+        - ` + transform + `
+`)
+
+		_, err := parseConfig(config)
+		errorContains(t, err, expectedError)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// units of the scraped values, see normalizeUnitValue
+const (
+	unitSI       = "si"
+	unitBytes    = "bytes"
+	unitPercent  = "percent"
+	unitDuration = "duration"
+)
+
+var units = map[string]bool{
+	"":           true,
+	unitSI:       true,
+	unitBytes:    true,
+	unitPercent:  true,
+	unitDuration: true,
+}
+
+var siMultipliers = map[string]float64{
+	"":   1,
+	"n":  1e-9,
+	"u":  1e-6,
+	"µ":  1e-6,
+	"m":  1e-3,
+	"k":  1e3,
+	"K":  1e3,
+	"M":  1e6,
+	"G":  1e9,
+	"T":  1e12,
+	"P":  1e15,
+	"E":  1e18,
+	"Ki": math.Pow(2, 10),
+	"Mi": math.Pow(2, 20),
+	"Gi": math.Pow(2, 30),
+	"Ti": math.Pow(2, 40),
+	"Pi": math.Pow(2, 50),
+	"Ei": math.Pow(2, 60),
+}
+
+// byte units are matched case-insensitively, since pages often show "mb" or "Kb" meaning bytes
+var byteMultipliers = map[string]float64{
+	"":      1,
+	"b":     1,
+	"byte":  1,
+	"bytes": 1,
+	"k":     1e3,
+	"kb":    1e3,
+	"m":     1e6,
+	"mb":    1e6,
+	"g":     1e9,
+	"gb":    1e9,
+	"t":     1e12,
+	"tb":    1e12,
+	"p":     1e15,
+	"pb":    1e15,
+	"kib":   math.Pow(2, 10),
+	"mib":   math.Pow(2, 20),
+	"gib":   math.Pow(2, 30),
+	"tib":   math.Pow(2, 40),
+	"pib":   math.Pow(2, 50),
+}
+
+var durationMultipliers = map[string]float64{
+	"ns":      1e-9,
+	"us":      1e-6,
+	"µs":      1e-6,
+	"ms":      1e-3,
+	"s":       1,
+	"sec":     1,
+	"secs":    1,
+	"second":  1,
+	"seconds": 1,
+	"m":       60,
+	"min":     60,
+	"mins":    60,
+	"minute":  60,
+	"minutes": 60,
+	"h":       3600,
+	"hr":      3600,
+	"hrs":     3600,
+	"hour":    3600,
+	"hours":   3600,
+	"d":       86400,
+	"day":     86400,
+	"days":    86400,
+	"w":       604800,
+	"week":    604800,
+	"weeks":   604800,
+}
+
+var durationComponentRegex = regexp.MustCompile(`([0-9][0-9.,]*)\s*([^\s0-9.,]+)`)
+
+// normalizeUnitValue parses a value with a unit suffix into a number in the base unit: bytes for `bytes`,
+// seconds for `duration` and a ratio for `percent`. `si` only applies the SI or IEC multiplier of the suffix
+func normalizeUnitValue(value string, unit string, parseNumber numberParser) (float64, error) {
+	switch unit {
+	case unitSI:
+		return normalizeSuffixedValue(value, siMultipliers, false, parseNumber)
+	case unitBytes:
+		return normalizeSuffixedValue(value, byteMultipliers, true, parseNumber)
+	case unitPercent:
+		number, err := parseNumber(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "%")))
+		if err != nil {
+			return 0, err
+		}
+
+		return number / 100, nil
+	case unitDuration:
+		return normalizeDurationValue(value, parseNumber)
+	}
+
+	return 0, newScrapeError(failureReasonNumberFormat, "unknown unit \"%s\"", unit)
+}
+
+func normalizeSuffixedValue(value string, multipliers map[string]float64, caseInsensitive bool, parseNumber numberParser) (float64, error) {
+	value = strings.TrimSpace(value)
+	number := strings.TrimRightFunc(value, func(r rune) bool {
+		return unicode.IsLetter(r)
+	})
+	suffix := value[len(number):]
+
+	if caseInsensitive {
+		suffix = strings.ToLower(suffix)
+	}
+
+	multiplier, found := multipliers[suffix]
+	if !found {
+		return 0, newScrapeError(failureReasonNumberFormat, "unknown unit suffix \"%s\" in value %s", suffix, value)
+	}
+
+	numberValue, err := parseNumber(strings.TrimSpace(number))
+	if err != nil {
+		return 0, err
+	}
+
+	return numberValue * multiplier, nil
+}
+
+// normalizeDurationValue parses durations with one or more components, like "45 ms" or "2h 13m", into seconds
+func normalizeDurationValue(value string, parseNumber numberParser) (float64, error) {
+	components := durationComponentRegex.FindAllStringSubmatch(value, -1)
+	if len(components) == 0 {
+		return 0, newScrapeError(failureReasonNumberFormat, "no duration found in value %s", value)
+	}
+
+	// everything but whitespace should be part of a component
+	if len(strings.Join(strings.Fields(durationComponentRegex.ReplaceAllString(value, "")), "")) > 0 {
+		return 0, newScrapeError(failureReasonNumberFormat, "invalid duration %s", value)
+	}
+
+	seconds := 0.0
+
+	for _, component := range components {
+		multiplier, found := durationMultipliers[strings.ToLower(component[2])]
+		if !found {
+			return 0, newScrapeError(failureReasonNumberFormat, "unknown duration unit \"%s\" in value %s", component[2], value)
+		}
+
+		number, err := parseNumber(component[1])
+		if err != nil {
+			return 0, err
+		}
+
+		seconds += number * multiplier
+	}
+
+	return seconds, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+)
+
+func assertFloatEquals(tb testing.TB, exp float64, act float64) {
+	tb.Helper()
+	assert(tb, math.Abs(exp-act) < 1e-9*math.Max(1, math.Abs(exp)), "expected %g, got %g", exp, act)
+}
+
+func TestNormalizeUnitValue(t *testing.T) {
+	tests := []struct {
+		value    string
+		unit     string
+		expected float64
+	}{
+		{"1.2k", "si", 1200},
+		{"3.4 M", "si", 3400000},
+		{"250m", "si", 0.25},
+		{"2 Ki", "si", 2048},
+		{"42", "si", 42},
+		{"512 MiB", "bytes", 512 * 1024 * 1024},
+		{"1.5 GB", "bytes", 1.5e9},
+		{"2kb", "bytes", 2000},
+		{"100 bytes", "bytes", 100},
+		{"42%", "percent", 0.42},
+		{"12.5 %", "percent", 0.125},
+		{"2h 13m", "duration", 2*3600 + 13*60},
+		{"45 ms", "duration", 0.045},
+		{"1 day 2 hours", "duration", 93600},
+		{"1m30s", "duration", 90},
+	}
+
+	for _, test := range tests {
+		value, err := normalizeUnitValue(test.value, test.unit, getNumberParser("", ",", "."))
+		ok(t, err)
+		assertFloatEquals(t, test.expected, value)
+	}
+}
+
+func TestNormalizeUnitValue_separators(t *testing.T) {
+	value, err := normalizeUnitValue("1.234,5 KiB", "bytes", getNumberParser("", ".", ","))
+	ok(t, err)
+	assertFloatEquals(t, 1234.5*1024, value)
+}
+
+func TestNormalizeUnitValue_errors(t *testing.T) {
+	tests := []struct {
+		value         string
+		unit          string
+		expectedError string
+	}{
+		{"3 X", "si", "unknown unit suffix \"X\""},
+		{"3 parsecs", "bytes", "unknown unit suffix \"parsecs\""},
+		{"2 fortnights", "duration", "unknown duration unit \"fortnights\""},
+		{"soon", "duration", "no duration found"},
+		{"2h foo 3", "duration", "invalid duration"},
+		{"about 42%", "percent", "error parsing value"},
+		{"42", "furlongs", "unknown unit \"furlongs\""},
+	}
+
+	for _, test := range tests {
+		_, err := normalizeUnitValue(test.value, test.unit, getNumberParser("", ",", "."))
+		assert(t, err != nil, "expected value %s with unit %s to fail", test.value, test.unit)
+		errorContains(t, err, test.expectedError)
+		equals(t, failureReasonNumberFormat, getFailureReason(err))
+	}
+}
+
+func TestScrape_unit(t *testing.T) {
+	server := getTestServer("<div id=\"foobar\">Uptime: 2h 13m</div>")
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Metrics = []types.MetricConfig{{
+		Name:       "uptime_seconds",
+		Unit:       "duration",
+		Transforms: []types.TransformConfig{{Type: "strip_prefix", Value: "Uptime: "}},
+	}}
+
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+	equals(t, 7980.0, output[0].value)
+}
+
+func TestParseConfig_unknownUnit(t *testing.T) {
+	config := []byte(`
+scrape_config:
+  address: "http://foo.dev"
+  metrics:
+    - name: foo
+      unit: furlongs
+`)
+
+	_, err := parseConfig(config)
+	errorContains(t, err, "unknown unit \"furlongs\" in metric \"foo\"")
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// stateLabel is the label holding the name of each state of a state set metric
+const stateLabel = "state"
+
+// makeMetricValues parses a scraped value into the samples of a metric: one per state for state set metrics,
+// or a single sample otherwise, which is always 1 for info metrics
+func makeMetricValues(value string, metricConfig types.MetricConfig, labels map[string]string) ([]metricValue, error) {
+	if metricConfig.Type == metricTypeInfo {
+		return []metricValue{{metricConfig: metricConfig, value: 1, labels: labels}}, nil
+	}
+
+	if metricConfig.Mode == metricModeLength {
+		numberValue, err := getLengthValue(value, metricConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		return []metricValue{{metricConfig: metricConfig, value: numberValue, labels: labels}}, nil
+	}
+
+	if len(metricConfig.States) == 0 {
+		numberValue, err := parseMetricValue(value, metricConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		return []metricValue{{metricConfig: metricConfig, value: numberValue, labels: labels}}, nil
+	}
+
+	return getStateSetValues(value, metricConfig, labels)
+}
+
+// getStateSetValues exports one sample per state of the metric, following the OpenMetrics StateSet convention:
+// the state matching the value is 1, and all the others are 0
+func getStateSetValues(value string, metricConfig types.MetricConfig, labels map[string]string) ([]metricValue, error) {
+	value, err := applyTransforms(value, metricConfig.Transforms)
+	if err != nil {
+		return nil, err
+	}
+
+	metricValues := make([]metricValue, len(metricConfig.States))
+	matchedState := false
+
+	for i, state := range metricConfig.States {
+		match := state.Match
+		if match == "" && state.Pattern == "" {
+			match = state.Name
+		}
+
+		matches, err := matchesValue(value, match, state.Pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		stateLabels := make(map[string]string, len(labels)+1)
+		for name, labelValue := range labels {
+			stateLabels[name] = labelValue
+		}
+		stateLabels[stateLabel] = state.Name
+
+		// a value matching more than one state only sets the first of them
+		numberValue := 0.0
+		if matches && !matchedState {
+			numberValue = 1
+			matchedState = true
+		}
+
+		metricValues[i] = metricValue{metricConfig: metricConfig, value: numberValue, labels: stateLabels}
+	}
+
+	if !matchedState {
+		log.Warnf("value '%s' of metric '%s' did not match any of its states", value, metricConfig.Name)
+	}
+
+	return metricValues, nil
+}
+
+// validateValueMappings checks the regular expressions of the value mappings and states of a metric,
+// compiling them so they are ready for the scrapes
+func validateValueMappings(metricConfig types.MetricConfig) error {
+	for i, mapping := range metricConfig.ValueMappings {
+		if mapping.Pattern == "" {
+			continue
+		}
+
+		if _, err := compileRegexp(mapping.Pattern); err != nil {
+			return fmt.Errorf("invalid regular expression `%s` in value mapping %d of metric \"%s\": %s", mapping.Pattern, i+1, metricConfig.Name, err)
+		}
+	}
+
+	for _, state := range metricConfig.States {
+		if state.Pattern == "" {
+			continue
+		}
+
+		if _, err := compileRegexp(state.Pattern); err != nil {
+			return fmt.Errorf("invalid regular expression `%s` in state \"%s\" of metric \"%s\": %s", state.Pattern, state.Name, metricConfig.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// mapMetricValue returns the number mapped to `value` by the value mappings of the metric, or its default value
+// if none of them matches. found is false when neither applies, so the value should be parsed as a number
+func mapMetricValue(value string, metricConfig types.MetricConfig) (number float64, found bool, err error) {
+	for _, mapping := range metricConfig.ValueMappings {
+		matches, err := matchesValue(value, mapping.Match, mapping.Pattern)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if matches {
+			return mapping.Value, true, nil
+		}
+	}
+
+	if metricConfig.DefaultValue != nil {
+		log.Debugf("value '%s' of metric '%s' did not match any value mapping, using the default value", value, metricConfig.Name)
+		return *metricConfig.DefaultValue, true, nil
+	}
+
+	return 0, false, nil
+}
+
+// matchesValue reports whether `value`, ignoring surrounding whitespace, is equal to `match`
+// or matches the regular expression `pattern`
+func matchesValue(value string, match string, pattern string) (bool, error) {
+	value = strings.TrimSpace(value)
+
+	if pattern == "" {
+		return value == match, nil
+	}
+
+	compiled, err := compileRegexp(pattern)
+	if err != nil {
+		return false, newScrapeError(failureReasonValueMapping, "invalid regular expression `%s`. error: %s", pattern, err)
+	}
+
+	return compiled.MatchString(value), nil
+}
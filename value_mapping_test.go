@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var testValueMappings = []types.ValueMappingConfig{
+	{Match: "Operational", Value: 0},
+	{Match: "Degraded", Value: 1},
+	{Pattern: "(?i)outage", Value: 2},
+}
+
+func TestParseMetricValue_valueMappings(t *testing.T) {
+	metricConfig := types.MetricConfig{ValueMappings: testValueMappings, DecimalPointSeparator: ".", ThousandsSeparator: ","}
+
+	tests := []struct {
+		value    string
+		expected float64
+	}{
+		{"Operational", 0},
+		{" Degraded\n", 1},
+		{"Major Outage", 2},
+		// values not matching any mapping are parsed as numbers
+		{"42", 42},
+	}
+
+	for _, test := range tests {
+		value, err := parseMetricValue(test.value, metricConfig)
+		ok(t, err)
+		equals(t, test.expected, value)
+	}
+
+	_, err := parseMetricValue("Maintenance", metricConfig)
+	equals(t, failureReasonNumberFormat, getFailureReason(err))
+}
+
+func TestParseMetricValue_defaultValue(t *testing.T) {
+	defaultValue := -1.0
+	metricConfig := types.MetricConfig{ValueMappings: testValueMappings, DefaultValue: &defaultValue}
+
+	value, err := parseMetricValue("Maintenance", metricConfig)
+	ok(t, err)
+	equals(t, -1.0, value)
+}
+
+func TestParseMetricValue_invalidValueMappingPattern(t *testing.T) {
+	metricConfig := types.MetricConfig{ValueMappings: []types.ValueMappingConfig{{Pattern: "(", Value: 1}}}
+
+	_, err := parseMetricValue("Operational", metricConfig)
+	errorContains(t, err, "invalid regular expression")
+	equals(t, failureReasonValueMapping, getFailureReason(err))
+}
+
+func TestParseConfig_invalidValueMappingPatterns(t *testing.T) {
+	config := []byte(`
+scrape_config:
+  address: "http://foo.dev"
+  metrics:
+    - name: foo
+      value_mappings:
+        - pattern: "("
+          value: 1
+`)
+
+	_, err := parseConfig(config)
+	errorContains(t, err, "invalid regular expression `(` in value mapping 1 of metric \"foo\"")
+
+	config = []byte(`
+scrape_config:
+  address: "http://foo.dev"
+  metrics:
+    - name: foo
+      states:
+        - name: up
+          pattern: "["
+`)
+
+	_, err = parseConfig(config)
+	errorContains(t, err, "invalid regular expression `[` in state \"up\" of metric \"foo\"")
+}
+
+func TestGetStateSetValues(t *testing.T) {
+	metricConfig := types.MetricConfig{
+		Name:       "status",
+		Transforms: []types.TransformConfig{{Type: "trim"}},
+		States: []types.StateConfig{
+			{Name: "operational", Match: "Operational"},
+			{Name: "degraded", Pattern: "(?i)degraded|partial"},
+			{Name: "Major Outage"},
+		},
+	}
+
+	values, err := getStateSetValues(" Partial Outage ", metricConfig, map[string]string{"component": "api"})
+	ok(t, err)
+	equals(t, 3, len(values))
+
+	expected := map[string]float64{"operational": 0, "degraded": 1, "Major Outage": 0}
+	for _, value := range values {
+		equals(t, "api", value.labels["component"])
+		equals(t, expected[value.labels[stateLabel]], value.value)
+	}
+
+	values, err = getStateSetValues("Major Outage", metricConfig, nil)
+	ok(t, err)
+	equals(t, 1.0, values[2].value)
+}
+
+func TestCollect_stateSet(t *testing.T) {
+	server := getTestServer("<div id=\"foobar\">Degraded</div>")
+
+	scrapeConfig := testScrapeConfig
+	scrapeConfig.Address = server.URL
+	scrapeConfig.Metrics = []types.MetricConfig{{
+		Name: "status",
+		Help: "Status of the service",
+		States: []types.StateConfig{
+			{Name: "operational", Match: "Operational"},
+			{Name: "degraded", Match: "Degraded"},
+		},
+	}}
+
+	collector := collector{ctx: context.Background(), scrapeConfigs: []types.ScrapeConfig{scrapeConfig}, globalConfig: testExporterConfig.GlobalConfig}
+
+	expected := `
+# HELP htmlexporter_status Status of the service
+# TYPE htmlexporter_status gauge
+htmlexporter_status{state="degraded"} 1
+htmlexporter_status{state="operational"} 0
+`
+
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "htmlexporter_status")
+	ok(t, err)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+	log "github.com/sirupsen/logrus"
+)
+
+// scrapeXML returns the metric values of an XML response body, selected by XPath expressions
+// using the namespace prefixes of the scrape config
+func scrapeXML(body []byte, config types.ScrapeConfig, stats *scrapeStats) ([]metricValue, error) {
+	start := time.Now()
+	doc, err := parseXMLDocument(body)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.addPhaseDuration(phaseParse, time.Since(start))
+
+	var metricValues []metricValue
+
+	for _, metricConfig := range getMetricConfigs(config) {
+		log.Debugf("scraping value from requested URL with XPath selector '%s'", metricConfig.Selector)
+
+		start = time.Now()
+		values, err := scrapeNodeMetric(xmlNode{node: doc, namespaces: config.Namespaces}, metricConfig, stats)
+		stats.addPhaseDuration(phaseXPath, time.Since(start))
+
+		if err != nil {
+			return nil, err
+		}
+
+		log.Debugf("scraped %d value(s) for metric '%s' from URL '%s'", len(values), metricConfig.Name, redactURL(config.Address))
+		metricValues = append(metricValues, values...)
+	}
+
+	return metricValues, nil
+}
+
+func parseXMLDocument(body []byte) (*xmlquery.Node, error) {
+	doc, err := xmlquery.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, newScrapeError(failureReasonParse, "error parsing the response body as XML. error: %s", err)
+	}
+
+	return doc, nil
+}
+
+// xmlNode is a node of an XML document, queried with XPath expressions using the namespace prefixes of the scrape config
+type xmlNode struct {
+	node       *xmlquery.Node
+	namespaces map[string]string
+}
+
+func (n xmlNode) query(selector string) ([]metricNode, error) {
+	nodes, err := findXMLNodes(n.node, selector, n.namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]metricNode, len(nodes))
+	for i, node := range nodes {
+		results[i] = xmlNode{node: node, namespaces: n.namespaces}
+	}
+
+	return results, nil
+}
+
+func (n xmlNode) text() (string, error) {
+	return getXMLNodeValue(n.node), nil
+}
+
+func (n xmlNode) metricValues(metricConfig types.MetricConfig, labels map[string]string) ([]metricValue, error) {
+	return makeMetricValues(getXMLNodeValue(n.node), metricConfig, labels)
+}
+
+func (n xmlNode) selectorDescription() string {
+	return "XPath expression"
+}
+
+// getXMLNodeValue returns the text of a node. whitespace around the text of XML elements is usually indentation,
+// so it's not part of the value
+func getXMLNodeValue(node *xmlquery.Node) string {
+	return strings.TrimSpace(node.InnerText())
+}
+
+// findXMLNodes returns the nodes matched by the XPath expression `selector`, which may be none. prefixes
+// in the expression are resolved with `namespaces`, so they don't need to match the prefixes of the document
+func findXMLNodes(doc *xmlquery.Node, selector string, namespaces map[string]string) ([]*xmlquery.Node, error) {
+	expr, err := xpath.CompileWithNS(selector, namespaces)
+	if err != nil {
+		return nil, newScrapeError(failureReasonXPath, "error querying the XPath expression `%s`. error: %s", selector, err)
+	}
+
+	return xmlquery.QuerySelectorAll(doc, expr), nil
+}
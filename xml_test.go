@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GusAntoniassi/prometheus-html-exporter/internal/pkg/types"
+)
+
+const testXMLDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <GetStatusResponse xmlns="urn:example:status">
+      <status>
+        <count>42</count>
+        <queue name="orders" size="1,234"/>
+        <queue name="emails" size="7"/>
+      </status>
+    </GetStatusResponse>
+  </soap:Body>
+</soap:Envelope>`
+
+func getTestXMLScrapeConfig(metrics ...types.MetricConfig) types.ScrapeConfig {
+	server := getTestServer(testXMLDocument)
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Format = "xml"
+	config.Namespaces = map[string]string{
+		"s":  "http://schemas.xmlsoap.org/soap/envelope/",
+		"ns": "urn:example:status",
+	}
+	config.Metrics = metrics
+
+	return config
+}
+
+func TestScrape_xml(t *testing.T) {
+	config := getTestXMLScrapeConfig(
+		types.MetricConfig{Name: "count", Selector: "//ns:status/ns:count"},
+		types.MetricConfig{Name: "envelope_count", Selector: "/s:Envelope/s:Body//ns:count/text()"},
+		types.MetricConfig{Name: "orders_size", Selector: "//ns:queue[@name='orders']/@size"},
+		types.MetricConfig{Name: "queues", Selector: "//ns:queue", Mode: "count"},
+		types.MetricConfig{Name: "unprefixed", Selector: "//count", Mode: "presence"},
+	)
+
+	output, stats, err := scrape(context.Background(), config)
+	ok(t, err)
+
+	// names without a prefix match elements of any namespace
+	expected := []float64{42, 42, 1234, 2, 1}
+	equals(t, len(expected), len(output))
+
+	for i, value := range expected {
+		equals(t, value, output[i].value)
+	}
+
+	equals(t, 2, stats.matchedNodes["queues"])
+}
+
+func TestScrape_xmlLabelSelectors(t *testing.T) {
+	config := getTestXMLScrapeConfig(types.MetricConfig{
+		Name:           "queue_size",
+		Selector:       "//ns:queue",
+		ValueSelector:  "@size",
+		LabelSelectors: map[string]string{"queue": "@name", "count": "../ns:count"},
+	})
+
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+	equals(t, 2, len(output))
+
+	equals(t, 7.0, output[1].value)
+	equals(t, map[string]string{"queue": "emails", "count": "42"}, output[1].labels)
+}
+
+func TestScrape_xmlDocumentPrefixes(t *testing.T) {
+	server := getTestServer(`<feed xmlns:x="urn:x"><x:item>3</x:item></feed>`)
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Format = "xml"
+	config.Metrics = []types.MetricConfig{{Name: "item", Selector: "//x:item"}}
+
+	// without namespaces configured, prefixes are matched as written in the document
+	output, _, err := scrape(context.Background(), config)
+	ok(t, err)
+	equals(t, 3.0, output[0].value)
+}
+
+func TestScrape_xmlErrors(t *testing.T) {
+	tests := []struct {
+		selector      string
+		expectedError string
+		reason        string
+	}{
+		{"//ns:missing", "no elements returned by the XPath expression", failureReasonXPath},
+		{"/`$/", "error querying the XPath expression", failureReasonXPath},
+		{"//ns:queue/@name", "error parsing value orders", failureReasonNumberFormat},
+	}
+
+	for _, test := range tests {
+		config := getTestXMLScrapeConfig(types.MetricConfig{Name: "foo", Selector: test.selector})
+
+		_, _, err := scrape(context.Background(), config)
+		errorContains(t, err, test.expectedError)
+		equals(t, test.reason, getFailureReason(err))
+	}
+}
+
+func TestScrape_xmlInvalidBody(t *testing.T) {
+	server := getTestServer("<status><count>1</status>")
+
+	config := testScrapeConfig
+	config.Address = server.URL
+	config.Format = "xml"
+
+	_, _, err := scrape(context.Background(), config)
+	errorContains(t, err, "error parsing the response body as XML")
+	equals(t, failureReasonParse, getFailureReason(err))
+}